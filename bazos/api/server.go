@@ -0,0 +1,411 @@
+// Package api exposes bazos search, ads, sections and saved watches over an HTTP/JSON API.
+package api
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/watch"
+)
+
+// DefaultCacheTTL is the time responses are cached when Server.CacheTTL is zero.
+const DefaultCacheTTL = 5 * time.Minute
+
+// DefaultCacheEntries is the number of cached responses when Server.CacheEntries is zero.
+const DefaultCacheEntries = 1000
+
+const (
+	// MaxPages is the maximum number of listing pages fetched by a single request.
+	MaxPages = bazos.DefaultMaxPages
+	// MaxResults is the maximum number of ads returned by a single request.
+	MaxResults = 200
+)
+
+// StatusClientClosedRequest is the status logged for requests canceled by the client.
+const StatusClientClosedRequest = 499
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Server serves the API. The zero value is not usable, Client must be set.
+//
+//	GET /api/search            ads matching the query parameters
+//	GET /api/feed              RSS (format=rss) or Atom (format=atom) feed of the search
+//	GET /api/ads/{id}          ad by ID
+//	GET /api/ad?url=           ad detail page at URL
+//	GET /api/sections          sections of the site
+//	GET /api/sections/{slug}   categories of the section
+//	GET /api/watches           saved watches
+//	GET /api/watches/{name}    ads seen by the watch
+//	GET /api/openapi.json      OpenAPI description
+type Server struct {
+	Client *bazos.Client
+	// Watches are the saved watches with results in WatchStore.
+	Watches    []watch.Watch
+	WatchStore *watch.Store
+	// CacheTTL is the time successful responses are cached. Zero means DefaultCacheTTL, negative disables caching.
+	CacheTTL time.Duration
+	// CacheEntries is the maximum number of cached responses, the least recently used
+	// response is evicted first. Zero means DefaultCacheEntries.
+	CacheEntries int
+
+	once    sync.Once
+	handler http.Handler
+	cache   *responseCache
+}
+
+// SearchResponse is the response of the search endpoint.
+type SearchResponse struct {
+	Ads []bazos.Ad
+	// Total is the number of ads reported by the site.
+	Total int
+	Pages int
+	// Truncated reports whether more ads were available than returned.
+	Truncated bool
+}
+
+// WatchResponse is the response of the watch endpoint.
+type WatchResponse struct {
+	Watch watch.Watch
+	Ads   []watch.SeenAd
+}
+
+// ErrorResponse is the response of failed requests.
+type ErrorResponse struct {
+	Error string
+}
+
+// errBadRequest marks errors caused by invalid request parameters.
+var errBadRequest = errors.New("bad request")
+
+// errNotFound marks errors caused by unknown resources.
+var errNotFound = errors.New("not found")
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.once.Do(s.init)
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) init() {
+	ttl := s.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	entries := s.CacheEntries
+	if entries <= 0 {
+		entries = DefaultCacheEntries
+	}
+	if ttl > 0 {
+		s.cache = newResponseCache(ttl, entries)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", s.cached(s.handleSearch))
+	mux.HandleFunc("/api/feed", s.cached(s.handleFeed))
+	mux.HandleFunc("/api/ads/", s.cached(s.handleAdByID))
+	mux.HandleFunc("/api/ad", s.cached(s.handleAd))
+	mux.HandleFunc("/api/sections", s.cached(s.handleSections))
+	mux.HandleFunc("/api/sections/", s.cached(s.handleSection))
+	mux.HandleFunc("/api/watches", s.handleWatches)
+	mux.HandleFunc("/api/watches/", s.handleWatch)
+	mux.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPISpec)
+	})
+	s.handler = mux
+}
+
+// handlerFunc handles request returning response value encoded as JSON, or rawResponse.
+type handlerFunc func(r *http.Request) (any, error)
+
+// rawResponse is a response body with its content type.
+type rawResponse struct {
+	contentType string
+	body        []byte
+}
+
+// cached wraps h with method check, JSON encoding and response cache.
+func (s *Server) cached(h handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+		if s.cache != nil {
+			if resp, ok := s.cache.get(key); ok {
+				w.Header().Set("X-Cache", "HIT")
+				w.Header().Set("Content-Type", resp.contentType)
+				_, _ = w.Write(resp.body)
+				return
+			}
+		}
+		v, err := h(r)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		resp, ok := v.(rawResponse)
+		if !ok {
+			body, err := json.Marshal(v)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			resp = rawResponse{contentType: "application/json", body: body}
+		}
+		if s.cache != nil {
+			s.cache.put(key, resp)
+			w.Header().Set("X-Cache", "MISS")
+		}
+		w.Header().Set("Content-Type", resp.contentType)
+		_, _ = w.Write(resp.body)
+	}
+}
+
+func (s *Server) handleSearch(r *http.Request) (any, error) {
+	params := r.URL.Query()
+	q, opts, err := parseSearchQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Client.ValidateQuery(r.Context(), q); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	it := s.Client.IterAds(r.Context(), q, opts)
+	ads, err := it.All()
+	if err != nil {
+		return nil, err
+	}
+	resp := SearchResponse{Ads: ads, Pages: it.Pages(), Truncated: it.Truncated()}
+	if resp.Ads == nil {
+		resp.Ads = []bazos.Ad{}
+	}
+	if page := it.Page(); page != nil {
+		resp.Total = page.Total
+	}
+	return resp, nil
+}
+
+func (s *Server) handleFeed(r *http.Request) (any, error) {
+	params := r.URL.Query()
+	q, opts, err := parseSearchQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Client.ValidateQuery(r.Context(), q); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	var (
+		write       func(*bazos.Feed, io.Writer) error
+		contentType string
+	)
+	switch format := params.Get("format"); format {
+	case "", "rss":
+		write, contentType = (*bazos.Feed).WriteRSS, "application/rss+xml; charset=utf-8"
+	case "atom":
+		write, contentType = (*bazos.Feed).WriteAtom, "application/atom+xml; charset=utf-8"
+	default:
+		return nil, fmt.Errorf("%w: invalid format %q, expected rss or atom", errBadRequest, format)
+	}
+	feed, err := s.Client.NewFeed(r.Context(), q, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := write(feed, &buf); err != nil {
+		return nil, err
+	}
+	return rawResponse{contentType: contentType, body: buf.Bytes()}, nil
+}
+
+func (s *Server) handleAdByID(r *http.Request) (any, error) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/ads/"), "/")
+	if id == "" || strings.Trim(id, "0123456789") != "" {
+		return nil, fmt.Errorf("%w: invalid ad ID %q", errBadRequest, id)
+	}
+	return s.Client.GetAdById(r.Context(), id)
+}
+
+func (s *Server) handleAd(r *http.Request) (any, error) {
+	u := r.URL.Query().Get("url")
+	parsed, err := url.Parse(u)
+	if u == "" || err != nil || !parsed.IsAbs() {
+		return nil, fmt.Errorf("%w: url parameter must be an absolute ad URL", errBadRequest)
+	}
+	host := parsed.Hostname()
+	if domain := s.Client.Domain(); host != domain && !strings.HasSuffix(host, "."+domain) {
+		return nil, fmt.Errorf("%w: url must point to %s", errBadRequest, domain)
+	}
+	return s.Client.GetAd(r.Context(), u)
+}
+
+func (s *Server) handleSections(r *http.Request) (any, error) {
+	site := s.Client.Site()
+	var sections []bazos.CatalogSection
+	for _, slug := range site.Sections {
+		if slug != bazos.AnySection {
+			sections = append(sections, bazos.CatalogSection{Slug: slug, Name: site.SectionName(slug)})
+		}
+	}
+	return sections, nil
+}
+
+func (s *Server) handleSection(r *http.Request) (any, error) {
+	slug := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/sections/"), "/")
+	if !s.Client.Site().HasSection(slug) || slug == bazos.AnySection {
+		return nil, fmt.Errorf("%w: unknown section %q", errNotFound, slug)
+	}
+	return s.Client.CatalogSection(r.Context(), slug)
+}
+
+func (s *Server) handleWatches(w http.ResponseWriter, r *http.Request) {
+	watches := s.Watches
+	if watches == nil {
+		watches = []watch.Watch{}
+	}
+	writeJSON(w, http.StatusOK, watches)
+}
+
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	name, err := url.PathUnescape(strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), "/api/watches/"), "/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var found *watch.Watch
+	for i := range s.Watches {
+		if s.Watches[i].Name == name {
+			found = &s.Watches[i]
+		}
+	}
+	if found == nil || s.WatchStore == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown watch %q", name))
+		return
+	}
+	seen, err := s.WatchStore.Load(name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := WatchResponse{Watch: *found, Ads: []watch.SeenAd{}}
+	for _, ad := range seen {
+		resp.Ads = append(resp.Ads, ad)
+	}
+	sort.Slice(resp.Ads, func(i, j int) bool {
+		return resp.Ads[i].FirstSeen.After(resp.Ads[j].FirstSeen)
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseSearchQuery parses search query and list options from request parameters.
+func parseSearchQuery(params url.Values) (bazos.SearchQuery, bazos.ListOptions, error) {
+	q := bazos.SearchQuery{
+		Query:        params.Get("q"),
+		Location:     params.Get("location"),
+		Vicinity:     bazos.DefaultVicinity,
+		Sort:         bazos.SortOrder(params.Get("sort")),
+		Match:        params.Get("match"),
+		ExcludeWords: params["exclude"],
+	}
+	var opts bazos.ListOptions
+	if section := params.Get("section"); section != "" {
+		q.Section = &bazos.AdSection{Section: section, Category: params.Get("category")}
+	}
+
+	ints := map[string]*int{
+		"vicinity":   &q.Vicinity,
+		"price_from": &q.PriceFrom,
+		"price_to":   &q.PriceTo,
+		"days":       &q.PostedWithinDays,
+		"min_views":  &q.MinViews,
+		"max_pages":  &opts.MaxPages,
+		"limit":      &opts.MaxResults,
+	}
+	for name, v := range ints {
+		if s := params.Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return q, opts, fmt.Errorf("%w: invalid %s %q", errBadRequest, name, s)
+			}
+			*v = n
+		}
+	}
+	// a single request must not crawl the whole result set
+	if opts.MaxPages == 0 || opts.MaxPages > MaxPages {
+		opts.MaxPages = MaxPages
+	}
+	if opts.MaxResults == 0 || opts.MaxResults > MaxResults {
+		opts.MaxResults = MaxResults
+	}
+	bools := map[string]*bool{
+		"exclude_top": &q.ExcludeTop,
+		"photo_only":  &q.PhotoOnly,
+		"collapse":    &opts.CollapseReposts,
+	}
+	for name, v := range bools {
+		if s := params.Get(name); s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return q, opts, fmt.Errorf("%w: invalid %s %q", errBadRequest, name, s)
+			}
+			*v = b
+		}
+	}
+	return q, opts, nil
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, errNotFound), errors.Is(err, bazos.ErrAdNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, bazos.ErrAdRemoved):
+		return http.StatusGone
+	case errors.Is(err, bazos.ErrBlocked):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		status, body = http.StatusInternalServerError, []byte(`{"Error":"failed to encode response"}`)
+	}
+	writeBody(w, status, body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= 500 {
+		logrus.Warnf("api request failed: %v", err)
+	}
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func writeBody(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/internal/bazostest"
+	"go.fabry.dev/fbot/bazos/watch"
+)
+
+func newTestAPI(t *testing.T) (*bazostest.Server, *Server) {
+	t.Helper()
+	srv := bazostest.NewServer(t, "../testdata")
+	client := bazos.NewClient(
+		bazos.WithHTTPClient(srv.HTTPClient()),
+		bazos.WithScheme("http"),
+		bazos.WithDomain("bazos.test"),
+		bazos.WithCrawlPolicy(bazos.CrawlPolicy{}),
+	)
+	return srv, &Server{Client: client}
+}
+
+func get(t *testing.T, h http.Handler, target string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: invalid JSON response %q: %v", target, rec.Body.String(), err)
+		}
+	}
+	return rec
+}
+
+func TestSearch(t *testing.T) {
+	srv, api := newTestAPI(t)
+	srv.Handle("elektro.bazos.test/search.php?hledat=electrolux&rubriky=elektro&crz=3", "search_electrolux_3.html")
+	srv.Handle("elektro.bazos.test/search.php", "search_electrolux.html")
+
+	var resp SearchResponse
+	rec := get(t, api, "/api/search?q=electrolux&section=elektro&price_to=200", &resp)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	// ads priced above 200 (including the in-text price) are filtered out
+	if len(resp.Ads) != 3 || resp.Pages != 2 || resp.Total != 5 {
+		t.Errorf("unexpected response: %d ads, %d pages, total %d", len(resp.Ads), resp.Pages, resp.Total)
+	}
+	if rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected cache miss, got %q", rec.Header().Get("X-Cache"))
+	}
+
+	requests := len(srv.Requests())
+	rec = get(t, api, "/api/search?section=elektro&price_to=200&q=electrolux", &resp)
+	if rec.Header().Get("X-Cache") != "HIT" || len(srv.Requests()) != requests {
+		t.Errorf("expected cached response")
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	cache := newResponseCache(time.Minute, 2)
+	for _, key := range []string{"a", "b"} {
+		cache.put(key, rawResponse{body: []byte(key)})
+	}
+	// a is used recently, so b is evicted
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	cache.put("c", rawResponse{body: []byte("c")})
+	if cache.len() != 2 {
+		t.Errorf("expected 2 cached responses, got %d", cache.len())
+	}
+	if _, ok := cache.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if resp, ok := cache.get(key); !ok || string(resp.body) != key {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+
+	expired := newResponseCache(-time.Minute, 2)
+	expired.put("a", rawResponse{})
+	if _, ok := expired.get("a"); ok || expired.len() != 0 {
+		t.Error("expected expired response to be removed")
+	}
+}
+
+func TestFeed(t *testing.T) {
+	srv, api := newTestAPI(t)
+	srv.Handle("elektro.bazos.test/", "section_elektro.html")
+	srv.Handle("elektro.bazos.test/pracky/", "search_electrolux_3.html")
+
+	for format, contentType := range map[string]string{
+		"rss":  "application/rss+xml; charset=utf-8",
+		"atom": "application/atom+xml; charset=utf-8",
+	} {
+		rec := get(t, api, "/api/feed?q=electrolux&section=elektro&category=pracky&format="+format, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get("Content-Type"); got != contentType {
+			t.Errorf("expected content type %q, got %q", contentType, got)
+		}
+		if !strings.Contains(rec.Body.String(), "Electrolux sušička") {
+			t.Errorf("expected ad in %s feed, got %s", format, rec.Body)
+		}
+	}
+	if rec := get(t, api, "/api/feed?q=x&format=xml", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid format, got %d", rec.Code)
+	}
+}
+
+func TestSearchBadRequest(t *testing.T) {
+	srv, api := newTestAPI(t)
+	srv.Handle("elektro.bazos.test/", "section_elektro.html")
+	for _, target := range []string{
+		"/api/search?q=x&section=elektro&category=pracka",
+		"/api/feed?q=x&section=elektro&category=pracka",
+		"/api/search?q=x&price_from=abc",
+		"/api/search?q=x&section=nope",
+		"/api/search?q=x&location=Bratislava",
+		"/api/search?q=x&photo_only=maybe",
+		"/api/search?q=x&max_pages=-1",
+		"/api/search?q=x&limit=-5",
+		"/api/search?q=x&price_to=-1",
+	} {
+		var resp ErrorResponse
+		rec := get(t, api, target, &resp)
+		if rec.Code != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("GET %s: expected status 400 with error, got %d %+v", target, rec.Code, resp)
+		}
+	}
+}
+
+func TestParseSearchQueryLimits(t *testing.T) {
+	tests := []struct {
+		params     string
+		maxPages   int
+		maxResults int
+	}{
+		{"q=x", MaxPages, MaxResults},
+		{"q=x&max_pages=3&limit=50", 3, 50},
+		{"q=x&max_pages=1000&limit=100000", MaxPages, MaxResults},
+	}
+	for _, test := range tests {
+		params, _ := url.ParseQuery(test.params)
+		_, opts, err := parseSearchQuery(params)
+		if err != nil {
+			t.Fatalf("%s: %v", test.params, err)
+		}
+		if opts.MaxPages != test.maxPages || opts.MaxResults != test.maxResults {
+			t.Errorf("%s: expected %d pages and %d results, got %+v", test.params, test.maxPages, test.maxResults, opts)
+		}
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("fetch: %w", context.Canceled), StatusClientClosedRequest},
+		{fmt.Errorf("fetch: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{fmt.Errorf("fetch: %w", bazos.ErrBlocked), http.StatusServiceUnavailable},
+		{errors.New("connection refused"), http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		if got := errorStatus(test.err); got != test.want {
+			t.Errorf("%v: expected status %d, got %d", test.err, test.want, got)
+		}
+	}
+}
+
+func TestAd(t *testing.T) {
+	srv, api := newTestAPI(t)
+	srv.Handle("/inzerat/150722248/predam-nosic.php", "ad_detail.html")
+
+	var ad bazos.Ad
+	target := "/api/ad?url=" + url.QueryEscape("http://auto.bazos.test/inzerat/150722248/predam-nosic.php")
+	if rec := get(t, api, target, &ad); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ad.ID != "150722248" {
+		t.Errorf("unexpected ad ID %q", ad.ID)
+	}
+
+	rec := get(t, api, "/api/ad?url="+url.QueryEscape("http://example.com/"), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for foreign URL, got %d", rec.Code)
+	}
+}
+
+func TestAdByID(t *testing.T) {
+	srv, api := newTestAPI(t)
+	srv.Handle("/inzerat/150722248/", "ad_detail.html")
+	srv.Handle("/inzerat/150000000/", "search_empty.html")
+	srv.Handle("/search.php", "search_empty.html")
+
+	var ad bazos.Ad
+	if rec := get(t, api, "/api/ads/150722248", &ad); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ad.UserName == "" {
+		t.Error("expected ad detail")
+	}
+	for target, status := range map[string]int{
+		"/api/ads/150000000": http.StatusGone,
+		"/api/ads/151111111": http.StatusNotFound,
+		"/api/ads/abc":       http.StatusBadRequest,
+	} {
+		if rec := get(t, api, target, nil); rec.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", target, status, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestSections(t *testing.T) {
+	srv, api := newTestAPI(t)
+	srv.Handle("elektro.bazos.test/", "section_elektro.html")
+
+	var sections []bazos.CatalogSection
+	get(t, api, "/api/sections", &sections)
+	if len(sections) != len(bazos.SiteSK.Sections)-1 {
+		t.Errorf("expected %d sections, got %d", len(bazos.SiteSK.Sections)-1, len(sections))
+	}
+
+	var section bazos.CatalogSection
+	if rec := get(t, api, "/api/sections/elektro", &section); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(section.Categories) != 5 {
+		t.Errorf("expected 5 categories, got %d", len(section.Categories))
+	}
+	if rec := get(t, api, "/api/sections/nope", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	_, api := newTestAPI(t)
+	store, err := watch.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := store.Save("pracky", map[string]watch.SeenAd{
+		"1": {Ad: bazos.Ad{ID: "1"}, FirstSeen: now.Add(-time.Hour)},
+		"2": {Ad: bazos.Ad{ID: "2"}, FirstSeen: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	api.Watches = []watch.Watch{{Name: "pracky", Query: bazos.SearchQuery{Query: "pracka"}}}
+	api.WatchStore = store
+
+	var resp WatchResponse
+	if rec := get(t, api, "/api/watches/pracky", &resp); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(resp.Ads) != 2 || resp.Ads[0].Ad.ID != "2" {
+		t.Errorf("expected 2 ads newest first, got %+v", resp.Ads)
+	}
+	if rec := get(t, api, "/api/watches/other", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	_, api := newTestAPI(t)
+	var spec map[string]any
+	get(t, api, "/api/openapi.json", &spec)
+	if spec["openapi"] == nil {
+		t.Error("expected OpenAPI document")
+	}
+}
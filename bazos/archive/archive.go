@@ -0,0 +1,302 @@
+// Package archive stores snapshots of ads together with their full resolution images
+// in a local directory, so they stay available after the ads are removed from the site.
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/internal/fsutil"
+)
+
+const (
+	manifestFile = "manifest.json"
+	adJSONFile   = "ad.json"
+	adYAMLFile   = "ad.yaml"
+	imagesDir    = "images"
+)
+
+// Image is an archived image of an ad.
+type Image struct {
+	URL string
+	// File is the path of the image relative to the ad directory.
+	File   string
+	SHA256 string
+	Size   int
+}
+
+// Manifest describes an archived ad.
+type Manifest struct {
+	ID    string
+	Site  string
+	Title string
+	Link  string `json:",omitempty"`
+	// Archived is the time the ad was archived first.
+	Archived time.Time
+	// Updated is the time of the last snapshot.
+	Updated time.Time
+	// Images are the archived images, including images no longer present in the ad.
+	Images []Image `json:",omitempty"`
+}
+
+// Archive keeps ads in a directory, one directory per ad: <dir>/<site>/<ad ID>/.
+type Archive struct {
+	dir    string
+	client *bazos.Client
+}
+
+// New returns an Archive in dir using client for downloading images, creating the directory if needed.
+func New(dir string, client *bazos.Client) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &Archive{dir: dir, client: client}, nil
+}
+
+// DefaultDir returns the default directory of the archive. Unlike stores of other packages
+// it is not in the cache directory, as archived ads cannot be fetched again.
+func DefaultDir() string {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "bazos-archive")
+}
+
+// AdDir returns the directory of ad in the directory of the site the ad is listed on.
+func (a *Archive) AdDir(ad *bazos.Ad) string {
+	return a.adDir(a.client.AdSite(ad), ad.ID)
+}
+
+func (a *Archive) adDir(site *bazos.Site, id string) string {
+	return filepath.Join(a.dir, site.Code, filepath.Base(id))
+}
+
+// Save stores snapshot of ad and downloads its images in full resolution. Images in the
+// manifest whose file is in the archive with the recorded size are not downloaded again,
+// and files with the same content are stored once. Archived files are not read again,
+// the manifest is trusted for their hashes.
+// Images that fail to download are reported by the returned error after the snapshot is saved.
+func (a *Archive) Save(ctx context.Context, ad *bazos.Ad) (*Manifest, error) {
+	if ad.ID == "" {
+		return nil, fmt.Errorf("ad without ID cannot be archived")
+	}
+	site := a.client.AdSite(ad)
+	dir := a.adDir(site, ad.ID)
+	if err := os.MkdirAll(filepath.Join(dir, imagesDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	m, err := a.loadManifest(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		m = &Manifest{Archived: now}
+	} else if err != nil {
+		return nil, err
+	}
+	m.ID, m.Site, m.Title, m.Link, m.Updated = ad.ID, site.Code, ad.Title, ad.Link, now
+
+	archived, byHash, err := archivedImages(dir, m.Images)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		images    []Image
+		failed    int
+		failedErr error
+	)
+	for _, u := range imageURLs(ad) {
+		if img, ok := archived[u]; ok {
+			logrus.Debugf("image %s already archived", u)
+			images = append(images, img)
+			delete(archived, u)
+			continue
+		}
+		data, err := a.client.FetchImage(ctx, u)
+		if err != nil {
+			logrus.Warnf("failed to archive image %s: %v", u, err)
+			failed++
+			if failedErr == nil {
+				failedErr = err
+			}
+			continue
+		}
+		img := Image{URL: u, SHA256: hashData(data), Size: len(data)}
+		if file, ok := byHash[img.SHA256]; ok {
+			img.File = file
+		} else {
+			img.File = path.Join(imagesDir, img.SHA256[:16]+imageExt(u, data))
+			if err := fsutil.WriteFileAtomic(filepath.Join(dir, filepath.FromSlash(img.File)), data, 0o644); err != nil {
+				return nil, err
+			}
+			byHash[img.SHA256] = img.File
+		}
+		images = append(images, img)
+	}
+	// keep images removed from the ad
+	var removed []Image
+	for _, img := range archived {
+		removed = append(removed, img)
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].URL < removed[j].URL })
+	m.Images = append(images, removed...)
+
+	if err := a.saveSnapshot(dir, ad, m); err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		return m, fmt.Errorf("failed to archive %d images: %w", failed, failedErr)
+	}
+	return m, nil
+}
+
+// Load returns archived snapshot of the ad with id listed on site and its manifest.
+func (a *Archive) Load(site *bazos.Site, id string) (*bazos.Ad, *Manifest, error) {
+	dir := a.adDir(site, id)
+	m, err := a.loadManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, adJSONFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	var ad bazos.Ad
+	if err := json.Unmarshal(data, &ad); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse archived ad %s: %w", id, err)
+	}
+	return &ad, m, nil
+}
+
+// List returns manifests of all archived ads listed on site.
+func (a *Archive) List(site *bazos.Site) ([]Manifest, error) {
+	entries, err := os.ReadDir(filepath.Join(a.dir, site.Code))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []Manifest
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m, err := a.loadManifest(filepath.Join(a.dir, site.Code, e.Name()))
+		if err != nil {
+			logrus.Warnf("skipping archived ad %s: %v", e.Name(), err)
+			continue
+		}
+		list = append(list, *m)
+	}
+	return list, nil
+}
+
+func (a *Archive) loadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func (a *Archive) saveSnapshot(dir string, ad *bazos.Ad, m *Manifest) error {
+	adJSON, err := json.MarshalIndent(ad, "", "  ")
+	if err != nil {
+		return err
+	}
+	adYAML, err := yaml.Marshal(ad)
+	if err != nil {
+		return err
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, adJSONFile), adJSON, 0o644); err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, adYAMLFile), adYAML, 0o644); err != nil {
+		return err
+	}
+	// manifest last, it marks complete snapshot
+	return fsutil.WriteFileAtomic(filepath.Join(dir, manifestFile), manifest, 0o644)
+}
+
+// archivedImages returns images of the manifest whose file is stored in the ad directory
+// with the recorded size, keyed by URL, and their files keyed by SHA-256 hash.
+func archivedImages(dir string, images []Image) (map[string]Image, map[string]string, error) {
+	archived := make(map[string]Image)
+	byHash := make(map[string]string)
+	for _, img := range images {
+		fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(img.File)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if !fi.Mode().IsRegular() || fi.Size() != int64(img.Size) {
+			logrus.Debugf("archived image %s does not match manifest", img.File)
+			continue
+		}
+		archived[img.URL] = img
+		byHash[img.SHA256] = img.File
+	}
+	return archived, byHash, nil
+}
+
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// imageURLs returns full resolution URLs of ad images without duplicates.
+func imageURLs(ad *bazos.Ad) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range append([]string{ad.Image}, ad.Images...) {
+		if u == "" {
+			continue
+		}
+		u = bazos.FullImageURL(u)
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// imageExt returns file extension of image from its URL or content.
+func imageExt(u string, data []byte) string {
+	if parsed, err := url.Parse(u); err == nil {
+		if ext := path.Ext(parsed.Path); ext != "" {
+			return ext
+		}
+	}
+	if exts, err := mime.ExtensionsByType(http.DetectContentType(data)); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/internal/bazostest"
+)
+
+func newTestArchive(t *testing.T) (*bazostest.Server, *Archive) {
+	t.Helper()
+	fixtures := t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fixtures, "photo.png"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := bazostest.NewServer(t, fixtures)
+	client := bazos.NewClient(
+		bazos.WithHTTPClient(srv.HTTPClient()),
+		bazos.WithScheme("http"),
+		bazos.WithDomain("bazos.test"),
+		bazos.WithCrawlPolicy(bazos.CrawlPolicy{}),
+	)
+	archive, err := New(t.TempDir(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, archive
+}
+
+func TestArchiveSave(t *testing.T) {
+	srv, archive := newTestArchive(t)
+	srv.Handle("/img/1/248/150722248.png", "photo.png")
+	srv.Handle("/img/2/248/150722248.png", "photo.png")
+
+	ad := &bazos.Ad{
+		ID:    "150722248",
+		Title: "Predám nosič bicyklov",
+		Images: []string{
+			"http://www.bazos.test/img/1t/248/150722248.png",
+			"http://www.bazos.test/img/2/248/150722248.png",
+		},
+	}
+	m, err := archive.Save(context.Background(), ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(m.Images))
+	}
+	if got := m.Images[0].URL; got != "http://www.bazos.test/img/1/248/150722248.png" {
+		t.Errorf("expected full resolution image URL, got %q", got)
+	}
+	if m.Images[0].File != m.Images[1].File {
+		t.Errorf("expected images with the same content stored once, got %q and %q", m.Images[0].File, m.Images[1].File)
+	}
+	files, _ := os.ReadDir(filepath.Join(archive.AdDir(ad), imagesDir))
+	if len(files) != 1 {
+		t.Errorf("expected 1 image file, got %d", len(files))
+	}
+
+	// already archived images are not downloaded again, removed images are kept
+	requests := len(srv.Requests())
+	ad.Images = ad.Images[:1]
+	if m, err = archive.Save(context.Background(), ad); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()) - requests; n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+	if len(m.Images) != 2 {
+		t.Errorf("expected 2 images kept in archive, got %d", len(m.Images))
+	}
+
+	site, _ := bazos.LookupSite("sk")
+	loaded, lm, err := archive.Load(site, ad.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Title != ad.Title || !lm.Archived.Equal(m.Archived) {
+		t.Errorf("unexpected loaded ad %+v, manifest %+v", loaded, lm)
+	}
+	list, err := archive.List(site)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != ad.ID {
+		t.Errorf("unexpected archive list %+v", list)
+	}
+}
+
+func TestArchiveSaveMissingImage(t *testing.T) {
+	_, archive := newTestArchive(t)
+
+	ad := &bazos.Ad{ID: "1", Images: []string{"http://www.bazos.test/img/1/1/1.jpg"}}
+	m, err := archive.Save(context.Background(), ad)
+	if err == nil {
+		t.Fatal("expected error for missing image")
+	}
+	if m == nil || len(m.Images) != 0 {
+		t.Errorf("expected manifest without images, got %+v", m)
+	}
+	site, _ := bazos.LookupSite("sk")
+	if _, _, err := archive.Load(site, ad.ID); err != nil {
+		t.Errorf("expected snapshot saved despite failed image: %v", err)
+	}
+}
+
+func TestArchiveSaveChecksImages(t *testing.T) {
+	srv, archive := newTestArchive(t)
+	srv.Handle("/img/1/248/150722248.png", "photo.png")
+
+	ad := &bazos.Ad{ID: "150722248", Images: []string{"http://www.bazos.test/img/1/248/150722248.png"}}
+	m, err := archive.Save(context.Background(), ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(archive.AdDir(ad), filepath.FromSlash(m.Images[0].File))
+
+	// a file not matching the manifest size is downloaded again
+	if err := os.WriteFile(file, []byte("corrupted"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	requests := len(srv.Requests())
+	if _, err = archive.Save(context.Background(), ad); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()) - requests; n != 1 {
+		t.Errorf("expected corrupted image downloaded again, got %d requests", n)
+	}
+
+	// a missing file is downloaded again
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	requests = len(srv.Requests())
+	if _, err = archive.Save(context.Background(), ad); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()) - requests; n != 1 {
+		t.Errorf("expected missing image downloaded again, got %d requests", n)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("expected image stored again: %v", err)
+	}
+}
+
+func TestArchiveSaveAdSite(t *testing.T) {
+	_, archive := newTestArchive(t)
+
+	ad := &bazos.Ad{ID: "180000001", Link: "https://auto.bazos.cz/inzerat/180000001/skoda-octavia.php"}
+	m, err := archive.Save(context.Background(), ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Site != "cz" {
+		t.Errorf("expected site of the ad link, got %q", m.Site)
+	}
+	if dir := archive.AdDir(ad); filepath.Base(filepath.Dir(dir)) != "cz" {
+		t.Errorf("expected ad directory in cz, got %s", dir)
+	}
+	if _, err := os.Stat(filepath.Join(archive.AdDir(ad), manifestFile)); err != nil {
+		t.Errorf("expected manifest in the ad site directory: %v", err)
+	}
+	cz, _ := bazos.LookupSite("cz")
+	if _, _, err := archive.Load(cz, ad.ID); err != nil {
+		t.Errorf("expected ad loaded from its site: %v", err)
+	}
+	if list, err := archive.List(cz); err != nil || len(list) != 1 {
+		t.Errorf("expected ad listed on its site, got %+v, %v", list, err)
+	}
+}
@@ -1,9 +1,10 @@
 package bazos
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -11,12 +12,12 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/gookit/color"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
 
 const (
+	// DefaultDomain is the domain of DefaultSite.
 	DefaultDomain   = "bazos.sk"
 	DefaultVicinity = 10
 )
@@ -27,181 +28,95 @@ const (
 )
 
 type Ad struct {
-	ID          string
-	Title       string
-	Date        time.Time `json:",omitempty" yaml:",omitempty"`
+	ID    string
+	Title string
+	// Date is the date the ad was posted.
+	Date time.Time `json:",omitempty" yaml:",omitempty"`
+	// Bumped is the date the ad was last moved up in listings, nil if it was not bumped.
+	Bumped      *time.Time `json:",omitempty" yaml:",omitempty"`
 	Link        string
 	Section     *AdSection `json:",omitempty" yaml:",omitempty"`
-	Price       float64
+	Price       Price
 	Description string
 	Image       string   `json:",omitempty" yaml:",omitempty"`
 	Images      []string `json:",omitempty" yaml:",omitempty"`
 
-	Location    string
-	PostCode    string
-	Email       string `json:",omitempty" yaml:",omitempty"`
-	UserName    string `json:",omitempty" yaml:",omitempty"`
+	Location string
+	PostCode string
+	Email    string `json:",omitempty" yaml:",omitempty"`
+	UserName string `json:",omitempty" yaml:",omitempty"`
+	UserLink string `json:",omitempty" yaml:",omitempty"`
+	// UserID is the seller ID from UserLink, shared by all ads of the seller.
+	UserID      string `json:",omitempty" yaml:",omitempty"`
 	PhoneNumber string `json:",omitempty" yaml:",omitempty"`
 
 	Views int `json:",omitempty" yaml:",omitempty"`
-}
-
-func GetAd(u string) (*Ad, error) {
-	logrus.Debugf("fetching ad from url: %v", u)
-
-	resp, err := http.Get(u)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch ad url: %w", err)
-	}
-	defer resp.Body.Close()
-
-	adUrl := resp.Request.URL.String()
-	if adUrl != u {
-		logrus.Debugf("ad URL: %v", adUrl)
-	}
+	// Top reports whether the ad is promoted (TOP) in listings.
+	Top bool `json:",omitempty" yaml:",omitempty"`
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+	// RepostOf is the ID of the original ad if the ad is its repost, see MarkReposts.
+	RepostOf string `json:",omitempty" yaml:",omitempty"`
 
-	ad, err := parseAdListing(strings.NewReader(string(body)))
-	if err != nil {
-		return nil, err
-	}
-	ad.Link = adUrl
+	// ParseErrors lists fields that failed to parse.
+	ParseErrors []ParseError `json:",omitempty" yaml:",omitempty"`
 
-	return ad, nil
+	phoneLink string
 }
 
-// GetAdById fetches an Ad with given id.
-func GetAdById(id string) (*Ad, error) {
-	if id == "" {
-		return nil, fmt.Errorf("invalid id")
-	}
-	log := logrus.WithField("id", id)
-
-	log.Debugf("fetching ad by id")
+// ParseError describes a field that failed to parse.
+type ParseError struct {
+	Field  string
+	Value  string `json:",omitempty" yaml:",omitempty"`
+	Reason string
+}
 
-	ads, err := Search(id)
-	if err != nil {
-		return nil, fmt.Errorf("searching id %q error: %w", id, err)
-	}
-	if len(ads) > 1 {
-		log.Warnf("got multiple (%v) search results for id %q", len(ads), id)
-	} else if len(ads) == 1 {
-		log.Debugf("found ad by id: %v", ads[0].Title)
-	} else {
-		return nil, fmt.Errorf("ad not found")
+func (e ParseError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("parsing %s failed: %s", e.Field, e.Reason)
 	}
-	ad := ads[0]
-	return &ad, nil
+	return fmt.Sprintf("parsing %s (%q) failed: %s", e.Field, e.Value, e.Reason)
 }
 
-func GetAdListings(u string) ([]Ad, error) {
-	/*logrus.Debugf("getting ad listings from url: %v", url)
-
-	  response, err := http.GetAdById(url)
-	  if err != nil {
-	  	fmt.Println("Error fetching URL:", err)
-	  	return nil, err
-	  }
-	  defer response.Body.Close()
-
-	  logrus.Debugf("adListings URL: %v", response.Request.URL)
-
-	  body, err := io.ReadAll(response.Body)
-	  if err != nil {
-	  	fmt.Println("Error reading response body:", err)
-	  	return nil, err
-	  }
-
-	  logrus.Tracef("parsing ad listings:\n%s", body)
-
-	  // Parse the ad listings
-	  adListingsPage, err := parseAdListingsPage(strings.NewReader(string(body)))
-	  if err != nil {
-	  	return nil, err
-	  }*/
-	var (
-		adListings     []Ad
-		adListingsPage *AdListingsPage
-	)
-	/*adListingsPage, err := getAdListingsPage(url)
-	  if err != nil {
-	  	return nil, err
-	  }
-
-	  adListings = append(adListings, adListingsPage.AdListings...)*/
+func (ad *Ad) addParseError(field, value string, reason any) {
+	ad.ParseErrors = append(ad.ParseErrors, ParseError{
+		Field:  field,
+		Value:  value,
+		Reason: fmt.Sprint(reason),
+	})
+}
 
-	listingUrl, err := url.Parse(u)
-	if err != nil {
-		return nil, err
+// HasPhoto reports whether the ad has at least one photo.
+func (ad Ad) HasPhoto() bool {
+	if ad.Image != "" {
+		return true
 	}
-
-	page := 0
-	for nextUrl := listingUrl.String(); nextUrl != ""; {
-		var err error
-		adListingsPage, err = getAdListingsPage(nextUrl)
-		if err != nil {
-			return nil, err
-		}
-
-		adListings = append(adListings, adListingsPage.AdListings...)
-
-		page++
-		if page >= 10 {
-			break
-		}
-
-		if adListingsPage.NextPage != "" {
-			next, err := listingUrl.Parse(adListingsPage.NextPage)
-			if err != nil {
-				return nil, err
-			}
-			nextUrl = next.String()
-		} else {
-			nextUrl = ""
+	for _, img := range ad.Images {
+		if img != "" {
+			return true
 		}
 	}
-
-	return adListings, nil
+	return false
 }
 
-func getAdListingsPage(u string) (*AdListingsPage, error) {
-	logrus.Debugf("fetching ad listings page from url: %v", u)
-
-	response, err := http.Get(u)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
-	}
-	defer response.Body.Close()
-
-	logrus.Debugf("response status: %v", response.Status)
-
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
-	}
-
-	logrus.Tracef("parsing ad listing page body:\n%s", color.Gray.Sprintf("%s", body))
-
-	// Parse the ad listings
-	adListingsPage, err := parseAdListingsPage(strings.NewReader(string(body)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse ad listings page: %w", err)
-	}
+// GetAd fetches an Ad from detail page at u using DefaultClient.
+func GetAd(u string) (*Ad, error) {
+	return DefaultClient.GetAd(context.Background(), u)
+}
 
-	logrus.Tracef("ad listings page %d, From: %d, To: %d, Total: %d, Next: %s",
-		adListingsPage.Page, adListingsPage.From, adListingsPage.To, adListingsPage.Total, adListingsPage.NextPage)
+// GetAdById fetches an Ad with given id using DefaultClient.
+func GetAdById(id string) (*Ad, error) {
+	return DefaultClient.GetAdById(context.Background(), id)
+}
 
-	return adListingsPage, nil
+// GetAdListings fetches ad listings from u using DefaultClient.
+func GetAdListings(u string) ([]Ad, error) {
+	return DefaultClient.ListAds(context.Background(), u)
 }
 
-func parseSection(doc *goquery.Document) (*AdSection, error) {
+func parseSection(doc *goquery.Document, sel *Selectors) (*AdSection, error) {
 	sections := make([]string, 0)
 
-	drobky := doc.Find(".drobky").Children()
+	drobky := doc.Find(sel.Breadcrumbs).Children()
 	drobky.Each(func(i int, s *goquery.Selection) {
 		if i == 0 {
 			return
@@ -221,17 +136,13 @@ func parseSection(doc *goquery.Document) (*AdSection, error) {
 	return section, nil
 }
 
-func parseAdID(s *goquery.Selection) (string, error) {
+func parseAdID(s *goquery.Selection, sel *Selectors) (string, error) {
 	adID := ""
-	s.Find(".inzeratynadpis a").Each(func(i int, s *goquery.Selection) {
+	s.Find(sel.Listings.AdLink).Each(func(i int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
 		if exists {
-			parsedURL, err := url.Parse(href)
-			if err == nil {
-				pathSegments := strings.Split(parsedURL.Path, "/")
-				if len(pathSegments) > 2 {
-					adID = pathSegments[2]
-				}
+			if id, ok := adIDFromURL(href); ok {
+				adID = id
 			}
 		}
 	})
@@ -243,24 +154,55 @@ func parseAdID(s *goquery.Selection) (string, error) {
 	return adID, nil
 }
 
+var adIDRegexp = regexp.MustCompile(`^\d+$`)
+
+// adIDFromURL extracts ad ID from ad URL path of any site, e.g. /inzerat/150722248/title.php
+// or /ogloszenie/150722248/title.php.
+func adIDFromURL(u string) (string, bool) {
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return "", false
+	}
+	pathSegments := strings.Split(parsedURL.Path, "/")
+	for i, seg := range pathSegments {
+		if isAdPath(seg) && i+1 < len(pathSegments) && adIDRegexp.MatchString(pathSegments[i+1]) {
+			return pathSegments[i+1], true
+		}
+	}
+	return "", false
+}
+
+// isAdPath reports whether seg is the ad path segment of a site.
+func isAdPath(seg string) bool {
+	for _, s := range sites {
+		if seg == s.AdPath {
+			return true
+		}
+	}
+	return false
+}
+
 type AdListingsPage struct {
 	AdListings []Ad
 	Page       int
 	From, To   int
 	Total      int
 	NextPage   string
+	// NoResults reports whether the page states that no ads match the search.
+	NoResults bool `json:",omitempty"`
+	// Diagnostics describes problems found while parsing the page.
+	Diagnostics Diagnostics
 }
 
-func parseAdListingsInfo(s string) (from, to, total int, err error) {
-	if strings.Contains(s, "Hľadaniu nevyhovujú žiadne inzeráty") {
-		return 0, 0, 0, fmt.Errorf("no ads found")
+func parseAdListingsInfo(s string, site *Site, sel *Selectors) (from, to, total int, err error) {
+	if strings.Contains(s, site.NoResults) {
+		return 0, 0, 0, ErrNoResults
 	}
 
-	re := regexp.MustCompile(`(\d+)-(\d+)\D+(\d+)`)
-	matches := re.FindStringSubmatch(s)
+	matches := sel.infoRegexp.FindStringSubmatch(s)
 	logrus.Tracef("parsing ad listing info: %q, Matches: %+v", s, matches)
 	if len(matches) != 4 {
-		return 0, 0, 0, fmt.Errorf("incorrect matches count")
+		return 0, 0, 0, fmt.Errorf("unexpected listings info format")
 	}
 	from, err = strconv.Atoi(matches[1])
 	if err != nil {
@@ -274,32 +216,45 @@ func parseAdListingsInfo(s string) (from, to, total int, err error) {
 	return
 }
 
-func parseAdListingsPage(r io.Reader) (*AdListingsPage, error) {
+func parseAdListingsPage(r io.Reader, site *Site, sel *Selectors, now time.Time) (*AdListingsPage, error) {
 	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
-	var from, to, total, page int
+	var (
+		from, to, total, page int
+		noResults, infoFound  bool
+		diag                  Diagnostics
+	)
 
 	// parse ad listings info
-	doc.Find(".listainzerat.inzeratyflex").Each(func(i int, s *goquery.Selection) {
-		s.Find(".inzeratynadpis").Each(func(i int, s *goquery.Selection) {
-			var err error
-			from, to, total, err = parseAdListingsInfo(s.Text())
-			if err != nil {
-				logrus.Warnf("failed to parse ad listings info: %v", err)
-			}
-		})
+	doc.Find(sel.Listings.Info).Each(func(i int, s *goquery.Selection) {
+		infoFound = true
+		var err error
+		from, to, total, err = parseAdListingsInfo(s.Text(), site, sel)
+		if errors.Is(err, ErrNoResults) {
+			noResults = true
+		} else if err != nil {
+			logrus.Debugf("failed to parse ad listings info: %v", err)
+			diag.ParseErrors = append(diag.ParseErrors, ParseError{
+				Field:  "ListingsInfo",
+				Value:  strings.TrimSpace(s.Text()),
+				Reason: err.Error(),
+			})
+		}
 	})
+	if !infoFound {
+		diag.addMissing("listings info")
+	}
 	// parse current page
-	doc.Find(".strankovani .cisla").Each(func(i int, s *goquery.Selection) {
+	doc.Find(sel.Listings.CurrentPage).Each(func(i int, s *goquery.Selection) {
 		page, _ = strconv.Atoi(s.Text())
 	})
 	// parse next page
 	nextPage := ""
-	doc.Find(".strankovani a").Each(func(i int, s *goquery.Selection) {
-		if strings.TrimSpace(s.Text()) == "Ďalšia" {
+	doc.Find(sel.Listings.PageLinks).Each(func(i int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) == site.NextPage {
 			if href, ok := s.Attr("href"); ok {
 				nextPage = href
 			}
@@ -307,134 +262,123 @@ func parseAdListingsPage(r io.Reader) (*AdListingsPage, error) {
 	})
 
 	// parse ad section
-	section, err := parseSection(doc)
+	section, err := parseSection(doc, sel)
 	if err != nil {
 		logrus.Debugf("failed to parse section: %v", err)
 	}
 
 	var listings []Ad
-	doc.Find(".inzeraty.inzeratyflex").Each(func(i int, s *goquery.Selection) {
-		adId, err := parseAdID(s)
+	doc.Find(sel.Listings.Ad).Each(func(i int, s *goquery.Selection) {
+		var ad Ad
+		adId, err := parseAdID(s, sel)
+		if err != nil {
+			logrus.Debugf("failed to parse ad ID: %v", err)
+			ad.addParseError("ID", "", err)
+		}
+		title := s.Find(sel.Listings.Title).Text()
+		link, _ := s.Find(sel.Listings.Title).Attr("href")
+		imageURL, _ := s.Find(sel.Listings.Image).Attr(sel.Listings.ImageAttr)
+		description := s.Find(sel.Listings.Description).Text()
+		dateStr := strings.TrimSpace(s.Find(sel.Listings.Date).First().Text())
+		date, err := parseAdDate(dateStr, site, sel, now)
 		if err != nil {
-			logrus.Warnf("failed to parse ad ID: %v", err)
+			logrus.Debugf("parsing date (%q) error: %v", dateStr, err)
+			ad.addParseError("Date", dateStr, err)
 		}
-		title := s.Find(".nadpis a").Text()
-		link, _ := s.Find(".nadpis a").Attr("href")
-		imageURL, _ := s.Find(".obrazek").Attr("src")
-		description := s.Find(".popis").Text()
-		dateStr := s.Find(".inzeratynadpis span").First().Text()
-		dateStr = strings.Trim(dateStr, " -")
-		dateStr = strings.TrimPrefix(dateStr, "TOP")
-		dateStr = strings.Trim(dateStr, " -")
-		dateStr = strings.TrimSpace(strings.Trim(dateStr, "[]"))
-		date, err := time.Parse("2.1. 2006", dateStr)
+		ad.Top = date.top
+		location, postCode := parseListingLocation(s.Find(sel.Listings.Location), sel)
+		viewsText := s.Find(sel.Listings.Views).Text()
+		views, err := parseViews(viewsText, sel)
 		if err != nil {
-			logrus.Warnf("parsing date error: %v", err)
+			ad.addParseError("Views", strings.TrimSpace(viewsText), err)
+		}
+		priceText := s.Find(sel.Listings.Price).Text()
+		price, err := parsePrice(priceText, site)
+		if err != nil {
+			logrus.Debugf("parsing price (%q) error: %v", priceText, err)
+			ad.addParseError("Price", priceText, err)
+		}
+		if price.Kind == PriceKindInText {
+			price.Amount, _ = extractPrice(description, sel)
 		}
-		loc, _ := s.Find(".inzeratylok").Html()
-		location := strings.ReplaceAll(loc, "<br/>", " ")
-		views := parseViews(s.Find(".inzeratyview").Text())
-		price := parsePrice(s.Find(".inzeratycena b").Text())
-
-		listings = append(listings, Ad{
-			ID:          adId,
-			Section:     section,
-			Title:       title,
-			Link:        link,
-			Images:      []string{imageURL},
-			Description: description,
-			Date:        date,
-			Price:       price,
-			Location:    location,
-			Views:       views,
-		})
+
+		ad.ID = adId
+		ad.Section = section
+		ad.Title = title
+		ad.Link = link
+		if imageURL != "" {
+			ad.Images = []string{imageURL}
+		}
+		ad.Description = description
+		ad.Date = date.posted
+		ad.Bumped = date.bumpedDate()
+		ad.Price = price
+		ad.Location = location
+		ad.PostCode = postCode
+		ad.Views = views
+
+		listings = append(listings, ad)
 	})
 
+	withoutID := 0
+	for i, ad := range listings {
+		for _, e := range ad.ParseErrors {
+			if e.Field == "ID" {
+				withoutID++
+			}
+			e.Field = fmt.Sprintf("AdListings[%d].%s", i, e.Field)
+			diag.ParseErrors = append(diag.ParseErrors, e)
+		}
+	}
+	if len(listings) == 0 && !noResults {
+		diag.addMissing("ad listings")
+	} else if len(listings) > 0 && withoutID == len(listings) {
+		diag.addMissing("ad links")
+	}
+
 	return &AdListingsPage{
-		AdListings: listings,
-		Page:       page,
-		From:       from,
-		To:         to,
-		Total:      total,
-		NextPage:   nextPage,
+		AdListings:  listings,
+		Page:        page,
+		From:        from,
+		To:          to,
+		Total:       total,
+		NextPage:    nextPage,
+		NoResults:   noResults,
+		Diagnostics: diag,
 	}, nil
 }
 
-func parseViews(text string) int {
-	viewsText := strings.TrimSpace(strings.TrimSuffix(text, "x"))
-	if viewsText == "" {
-		return 0
-	}
-	views, err := strconv.Atoi(viewsText)
-	if err != nil {
-		logrus.Warnf("parsing views (%q) error: %v", viewsText, err)
+// parseListingLocation parses town and postcode separated by line break.
+func parseListingLocation(s *goquery.Selection, sel *Selectors) (town, postCode string) {
+	var parts []string
+	s.Contents().Each(func(i int, s *goquery.Selection) {
+		if t := strings.TrimSpace(s.Text()); t != "" {
+			parts = append(parts, t)
+		}
+	})
+	for _, p := range parts {
+		if postCode == "" && isPostCode(p, sel) {
+			postCode = p
+		} else if town == "" {
+			town = p
+		}
 	}
-	return views
+	return town, postCode
 }
 
-func parsePrice(text string) float64 {
-	priceText := strings.ReplaceAll(text, " ", "")
-	priceText = strings.TrimSpace(strings.TrimSuffix(priceText, "€"))
-	if priceText == "" || priceText == PriceFree {
-		return 0
-	} else if priceText == PriceInText {
-		return -1
-	}
-	price, err := strconv.ParseFloat(priceText, 64)
-	if err != nil {
-		logrus.Warnf("parsing price (%q) error: %v", priceText, err)
-	}
-	return price
+func isPostCode(s string, sel *Selectors) bool {
+	return sel.postCodeRegexp.MatchString(strings.TrimSpace(s))
 }
 
-func parseAdListing(r io.Reader) (*Ad, error) {
-	doc, err := goquery.NewDocumentFromReader(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+func parseViews(text string, sel *Selectors) (int, error) {
+	viewsText := strings.TrimSpace(text)
+	if viewsText == "" {
+		return 0, nil
 	}
-
-	section, err := parseSection(doc)
-	if err != nil {
-		logrus.Debugf("failed to parse section: %v", err)
+	if m := sel.viewsRegexp.FindString(viewsText); m != "" {
+		viewsText = strings.Join(strings.Fields(m), "")
 	}
-
-	title := doc.Find(".inzeratydetnadpis H1").Text()
-	dateStr := doc.Find(".inzeratydetnadpis span").Text()
-	date := parseDate(dateStr)
-	description := doc.Find(".popisdetail").Text()
-
-	userName := doc.Find("table table tr td a").First().Text()
-	phoneNumber := "Please manually fetch the phone number."
-	location := doc.Find("table table tr td a").Last().Text()
-
-	views := parseViews(doc.Find("table table tr td").Eq(11).Text())
-	price := parsePrice(doc.Find("table table tr td b").Last().Text())
-
-	var images []string
-	doc.Find(".carousel-cell img").Each(func(i int, s *goquery.Selection) {
-		src, _ := s.Attr("data-flickity-lazyload")
-		images = append(images, src)
-	})
-
-	return &Ad{
-		Section:     section,
-		Title:       title,
-		Date:        date,
-		UserName:    userName,
-		PhoneNumber: phoneNumber,
-		Location:    location,
-		Views:       views,
-		Price:       price,
-		Description: description,
-		Images:      images,
-	}, nil
-}
-
-func parseDate(dateStr string) time.Time {
-	dateStr = strings.Trim(dateStr, " -[]")
-	layout := "2.1. 2006"
-	date, _ := time.Parse(layout, dateStr)
-	return date
+	return strconv.Atoi(viewsText)
 }
 
 func toYaml(v any) string {
@@ -1,14 +1,26 @@
 package bazos
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 )
 
-var trace = flag.Bool("debug", false, "Enable trace log level")
+var (
+	trace  = flag.Bool("debug", false, "Enable trace log level")
+	live   = flag.Bool("live", false, "Run tests against live bazos.sk")
+	update = flag.Bool("update", false, "Update golden files")
+)
 
 func TestMain(m *testing.M) {
 	flag.Parse()
@@ -20,7 +32,471 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+func skipUnlessLive(t *testing.T) {
+	t.Helper()
+	if !*live {
+		t.Skip("skipping live test, use -live to enable")
+	}
+}
+
+// testNow is the time fixtures are parsed at.
+var testNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// defaultSelectors returns the embedded selectors of site.
+func defaultSelectors(site *Site) *Selectors {
+	return DefaultSelectorProfile().ForSite(site)
+}
+
+func checkGolden(t *testing.T, name string, got any) {
+	t.Helper()
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create): %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s does not match golden file %s\n got:\n%s\nwant:\n%s", name, path, data, want)
+	}
+}
+
+func TestParseAdListingsPage(t *testing.T) {
+	tests := []struct {
+		fixture string
+		site    *Site
+	}{
+		{"search_electrolux", SiteSK},
+		{"search_electrolux_3", SiteSK},
+		{"search_empty", SiteSK},
+		{"search_cz_skoda", SiteCZ},
+		{"search_pl_rower", SitePL},
+		{"search_at_fahrrad", SiteAT},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			page, err := parseAdListingsPage(openFixture(t, test.fixture+".html"), test.site, defaultSelectors(test.site), testNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "listings_"+test.fixture, page)
+		})
+	}
+}
+
+func TestParseAdListing(t *testing.T) {
+	tests := []struct {
+		fixture string
+		site    *Site
+	}{
+		{"ad_detail", SiteSK},
+		{"ad_detail_intext", SiteSK},
+		{"ad_detail_pl", SitePL},
+		{"ad_detail_at", SiteAT},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			ad, err := parseAdListing(openFixture(t, test.fixture+".html"), test.site, defaultSelectors(test.site), testNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "ad_"+test.fixture, ad)
+		})
+	}
+}
+
+func TestParseSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		want    *AdSection
+		wantErr bool
+	}{
+		{
+			name: "section and category",
+			html: `<div class="drobky"><a href="/">Hlavná stránka</a> &gt; <a href="/">Elektro</a> &gt; <a href="/pracky/">Práčky</a></div>`,
+			want: &AdSection{Category: "Elektro", Section: "Práčky"},
+		},
+		{
+			name: "section only",
+			html: `<div class="drobky"><a href="/">Hlavná stránka</a> &gt; <a href="/">Auto</a></div>`,
+			want: &AdSection{Category: "Auto"},
+		},
+		{
+			name:    "home only",
+			html:    `<div class="drobky"><a href="/">Hlavná stránka</a></div>`,
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			html:    `<div></div>`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseSection(doc, defaultSelectors(SiteSK))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error: %v, got: %v", test.wantErr, err)
+			}
+			if test.want != nil && *got != *test.want {
+				t.Errorf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseAdID(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "relative link",
+			html: `<div class="inzeratynadpis"><a href="/inzerat/151234567/predam-pracku.php">x</a></div>`,
+			want: "151234567",
+		},
+		{
+			name: "absolute link",
+			html: `<div class="inzeratynadpis"><a href="https://elektro.bazos.sk/inzerat/151234567/predam-pracku.php">x</a></div>`,
+			want: "151234567",
+		},
+		{
+			name: "pl link",
+			html: `<div class="inzeratynadpis"><a href="https://sport.bazos.pl/ogloszenie/412345678/rower.php">x</a></div>`,
+			want: "412345678",
+		},
+		{
+			name: "at link",
+			html: `<div class="inzeratynadpis"><a href="/anzeige/98765432/fahrrad.php">x</a></div>`,
+			want: "98765432",
+		},
+		{
+			name:    "no link",
+			html:    `<div class="inzeratynadpis">x</div>`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseAdID(doc.Selection, defaultSelectors(SiteSK))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error: %v, got: %v", test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text    string
+		site    *Site
+		want    Price
+		wantErr bool
+	}{
+		{"150 €", SiteSK, Price{Amount: 150, Currency: "EUR", Kind: PriceKindFixed}, false},
+		{"1 250 €", SiteSK, Price{Amount: 1250, Currency: "EUR", Kind: PriceKindFixed}, false},
+		{"1\u00a0250 €", SiteSK, Price{Amount: 1250, Currency: "EUR", Kind: PriceKindFixed}, false},
+		{"Zadarmo", SiteSK, Price{Currency: "EUR", Kind: PriceKindFree}, false},
+		{"V texte", SiteSK, Price{Currency: "EUR", Kind: PriceKindInText}, false},
+		{"Dohodou", SiteSK, Price{Currency: "EUR", Kind: PriceKindNegotiable}, false},
+		{"", SiteSK, Price{Currency: "EUR"}, true},
+		{"  ", SiteSK, Price{Currency: "EUR"}, true},
+		{"abc €", SiteSK, Price{Currency: "EUR"}, true},
+		{"35 000 Kč", SiteCZ, Price{Amount: 35000, Currency: "CZK", Kind: PriceKindFixed}, false},
+		{"Zdarma", SiteCZ, Price{Currency: "CZK", Kind: PriceKindFree}, false},
+		{"V textu", SiteCZ, Price{Currency: "CZK", Kind: PriceKindInText}, false},
+		{"1 200 zł", SitePL, Price{Amount: 1200, Currency: "PLN", Kind: PriceKindFixed}, false},
+		{"W tekście", SitePL, Price{Currency: "PLN", Kind: PriceKindInText}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got, err := parsePrice(test.text, test.site)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error: %v, got: %v", test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("parsePrice(%q) = %+v, want %+v", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestExtractPrice(t *testing.T) {
+	tests := []struct {
+		text   string
+		site   *Site
+		want   float64
+		wantOk bool
+	}{
+		{"Predám práčku, cena 220 € alebo dohodou.", SiteSK, 220, true},
+		{"Cena: 1 250 eur", SiteSK, 1250, true},
+		{"Cena 1.250,- EUR", SiteSK, 1250, true},
+		{"Model 2019, 6 kg", SiteSK, 0, false},
+		{"Cena 35 000 Kč", SiteCZ, 35000, true},
+		{"Cena 35000,- kc", SiteCZ, 35000, true},
+		{"Cena 300 zł", SitePL, 300, true},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got, ok := extractPrice(test.text, defaultSelectors(test.site))
+			if ok != test.wantOk || got != test.want {
+				t.Errorf("extractPrice(%q) = %v, %v, want %v, %v", test.text, got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestPriceMarshal(t *testing.T) {
+	price := Price{Amount: 150, Currency: "EUR", Kind: PriceKindFixed}
+
+	data, err := json.Marshal(price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Amount":150,"Currency":"EUR","Kind":"fixed"}`; string(data) != want {
+		t.Errorf("expected JSON %s, got %s", want, data)
+	}
+	var fromJSON Price
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON != price {
+		t.Errorf("JSON round trip: expected %+v, got %+v", price, fromJSON)
+	}
+
+	data, err = yaml.Marshal(price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML Price
+	if err := yaml.Unmarshal(data, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if fromYAML != price {
+		t.Errorf("YAML round trip: expected %+v, got %+v", price, fromYAML)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Kind":"bogus"}`), &fromJSON); err == nil {
+		t.Error("expected error for invalid price kind")
+	}
+}
+
+func TestSortByPrice(t *testing.T) {
+	ads := []Ad{
+		{ID: "intext", Price: Price{Kind: PriceKindInText}},
+		{ID: "200", Price: Price{Amount: 200, Kind: PriceKindFixed}},
+		{ID: "free", Price: Price{Kind: PriceKindFree}},
+		{ID: "50", Price: Price{Amount: 50, Kind: PriceKindFixed}},
+	}
+	SortByPrice(ads, false)
+	var got []string
+	for _, ad := range ads {
+		got = append(got, ad.ID)
+	}
+	if want := "free,50,200,intext"; strings.Join(got, ",") != want {
+		t.Errorf("expected order %s, got %s", want, strings.Join(got, ","))
+	}
+
+	filtered := FilterAds(ads, PriceBetween(10, 100, false))
+	if len(filtered) != 1 || filtered[0].ID != "50" {
+		t.Errorf("unexpected filtered ads: %+v", filtered)
+	}
+}
+
+func TestParseViews(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{"231 x", 231, false},
+		{"0 x", 0, false},
+		{"", 0, false},
+		{"abc x", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got, err := parseViews(test.text, defaultSelectors(SiteSK))
+			if got != test.want || (err != nil) != test.wantErr {
+				t.Errorf("parseViews(%q) = %v, %v, want %v (error %v)", test.text, got, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchQueryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   SearchQuery
+		wantErr bool
+	}{
+		{"empty", SearchQuery{}, false},
+		{"full", SearchQuery{Section: &AdSection{Section: "elektro", Category: "chladnicky"}, Location: "811 01", Vicinity: 25, PriceFrom: 100, PriceTo: 500}, false},
+		{"open range", SearchQuery{PriceFrom: 100}, false},
+		{"unknown section", SearchQuery{Section: &AdSection{Section: "nope"}}, true},
+		{"category without section", SearchQuery{Section: &AdSection{Category: "chladnicky"}}, true},
+		{"invalid postcode", SearchQuery{Location: "Bratislava"}, true},
+		{"short postcode", SearchQuery{Location: "8110"}, true},
+		{"negative vicinity", SearchQuery{Vicinity: -1}, true},
+		{"inverted range", SearchQuery{PriceFrom: 500, PriceTo: 100}, true},
+		{"at postcode", SearchQuery{Site: "at", Location: "1010"}, false},
+		{"unknown site", SearchQuery{Site: "xx"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.query.Validate(SiteSK)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFullImageURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.bazos.sk/img/1t/567/151234567.jpg":       "https://www.bazos.sk/img/1/567/151234567.jpg",
+		"https://www.bazos.sk/img/3t/567/151234567.jpg?t=123": "https://www.bazos.sk/img/3/567/151234567.jpg?t=123",
+		"https://www.bazos.sk/img/1/248/150722248.jpg":        "https://www.bazos.sk/img/1/248/150722248.jpg",
+		"": "",
+	}
+	for u, want := range tests {
+		if got := FullImageURL(u); got != want {
+			t.Errorf("FullImageURL(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestSearchQueryFilter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	ads := []Ad{
+		{ID: "1", Title: "Práčka Electrolux", Date: time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC), Views: 231, Top: true, Images: []string{"1.jpg"}},
+		{ID: "2", Title: "Práčka na diely", Description: "Nefunkčná", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Bumped: timePtr(time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)), Views: 12},
+		{ID: "3", Title: "Sušička Electrolux", Date: time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC), Views: 87, Images: []string{"3.jpg"}},
+	}
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string
+	}{
+		{"exclude top", SearchQuery{ExcludeTop: true}, []string{"2", "3"}},
+		{"posted within", SearchQuery{PostedWithinDays: 3}, []string{"1", "2", "3"}},
+		{"posted within bumped", SearchQuery{PostedWithinDays: 2}, []string{"1", "2"}},
+		{"exclude words", SearchQuery{ExcludeWords: []string{"DIELY", "sušička"}}, []string{"1"}},
+		{"match", SearchQuery{Match: `(?i)electrolux`}, []string{"1", "3"}},
+		{"min views", SearchQuery{MinViews: 50}, []string{"1", "3"}},
+		{"photo only", SearchQuery{PhotoOnly: true}, []string{"1", "3"}},
+		{"combined", SearchQuery{ExcludeTop: true, PhotoOnly: true, PostedWithinDays: 7}, []string{"3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := test.query.Filter(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ad := range FilterAds(ads, filter) {
+				got = append(got, ad.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(test.want, ",") {
+				t.Errorf("expected ads %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	if filter, err := (SearchQuery{Query: "pracka"}).Filter(now); filter != nil || err != nil {
+		t.Errorf("expected no filter for query without filters, got error %v", err)
+	}
+	if _, err := (SearchQuery{Match: "("}).Filter(now); err == nil {
+		t.Error("expected error for invalid match expression")
+	}
+}
+
+func TestSearchQueryMarshal(t *testing.T) {
+	q := SearchQuery{
+		Query:            "pracka",
+		Section:          &AdSection{Section: "elektro", Category: "pracky"},
+		Sort:             SortPriceAsc,
+		ExcludeTop:       true,
+		PostedWithinDays: 7,
+		ExcludeWords:     []string{"diely"},
+		Match:            `(?i)electrolux|bosch`,
+		MinViews:         10,
+		PhotoOnly:        true,
+	}
+
+	data, err := yaml.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYaml SearchQuery
+	if err := yaml.Unmarshal(data, &fromYaml); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYaml, q) {
+		t.Errorf("YAML round trip mismatch:\n got: %+v\nwant: %+v", fromYaml, q)
+	}
+
+	data, err = json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJson SearchQuery
+	if err := json.Unmarshal(data, &fromJson); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJson, q) {
+		t.Errorf("JSON round trip mismatch:\n got: %+v\nwant: %+v", fromJson, q)
+	}
+
+	u, err := q.toUrl("https", "bazos.sk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("order"); got != "1" {
+		t.Errorf("expected order parameter %q, got %q", "1", got)
+	}
+}
+
 func TestAdListing(t *testing.T) {
+	skipUnlessLive(t)
+
 	const adUrl = "https://auto.bazos.sk/inzerat/150722248/predam-nosic-bicyklov-na-tazne.php"
 
 	t.Logf("getting ad listing: %v", adUrl)
@@ -33,6 +509,8 @@ func TestAdListing(t *testing.T) {
 }
 
 func TestAdListings(t *testing.T) {
+	skipUnlessLive(t)
+
 	const adUrl = "https://bazos.sk/search.php?hledat=&rubriky=elektro&hlokalita=84107&humkreis=50&cenaod=&cenado=&Submit=H%C4%BEada%C5%A5"
 	// const adUrl = "https://elektro.bazos.sk/pracky/?hledat=&rubriky=elektro&hlokalita=84107&humkreis=50&cenaod=&cenado=&Submit=H%C4%BEada%C5%A5&kitx=ano"
 
@@ -51,6 +529,8 @@ func TestAdListings(t *testing.T) {
 }
 
 func TestSearch(t *testing.T) {
+	skipUnlessLive(t)
+
 	search := SearchQuery{
 		Query:    "electrolux",
 		Vicinity: 25,
@@ -0,0 +1,317 @@
+package bazos
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.fabry.dev/fbot/internal/fsutil"
+)
+
+// CacheEntry is a cached response body.
+type CacheEntry struct {
+	// URL is the final URL of the response after redirects.
+	URL  string
+	Body []byte
+	// ETag and LastModified are validators used for conditional requests once the entry expires.
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+	// Fetched is the time the response was fetched or last revalidated.
+	Fetched time.Time
+}
+
+// Cache stores responses keyed by request URL. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+}
+
+// CacheTTL is the time responses are used without asking the server, per page type.
+// Zero disables caching of the page type.
+type CacheTTL struct {
+	// Listing is the TTL of search and category listing pages.
+	Listing time.Duration
+	// Ad is the TTL of ad detail pages.
+	Ad time.Duration
+	// Section is the TTL of section pages used to discover categories.
+	Section time.Duration
+	// Image is the TTL of ad images.
+	Image time.Duration
+}
+
+// DefaultCacheTTL is the cache TTL used by default. Listings change often, while images
+// of an ad never change.
+var DefaultCacheTTL = CacheTTL{
+	Listing: 5 * time.Minute,
+	Ad:      time.Hour,
+	Section: 24 * time.Hour,
+	Image:   7 * 24 * time.Hour,
+}
+
+// pageKind is the type of fetched page deciding its cache TTL.
+type pageKind int
+
+const (
+	pageListing pageKind = iota
+	pageAd
+	pageSection
+	pageImage
+	// pagePhone is never cached, revealed phone numbers are bound to the session.
+	pagePhone
+	// pageHealth is never cached, health checks need current pages.
+	pageHealth
+)
+
+func (t CacheTTL) forKind(kind pageKind) time.Duration {
+	switch kind {
+	case pageListing:
+		return t.Listing
+	case pageAd:
+		return t.Ad
+	case pageSection:
+		return t.Section
+	case pageImage:
+		return t.Image
+	default:
+		return 0
+	}
+}
+
+// CacheStats are counters of cache lookups made by the client.
+type CacheStats struct {
+	// Hits is the number of responses served from the cache without request.
+	Hits int64
+	// Revalidated is the number of expired entries confirmed unchanged by the server.
+	Revalidated int64
+	// Misses is the number of responses fetched in full.
+	Misses int64
+}
+
+type cacheCounters struct {
+	hits, revalidated, misses atomic.Int64
+}
+
+// CacheStats returns cache statistics of the client.
+func (c *Client) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:        c.cacheStats.hits.Load(),
+		Revalidated: c.cacheStats.revalidated.Load(),
+		Misses:      c.cacheStats.misses.Load(),
+	}
+}
+
+// MemoryCache is an in-memory Cache evicting the least recently used entries.
+type MemoryCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// DefaultMemoryCacheEntries is the default capacity of MemoryCache.
+const DefaultMemoryCacheEntries = 1000
+
+// NewMemoryCache returns a MemoryCache holding up to maxEntries entries,
+// DefaultMemoryCacheEntries if maxEntries is not positive.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMemoryCacheEntries
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns entry for key and marks it as recently used.
+func (m *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return el.Value.(*memoryCacheItem).entry, true
+}
+
+// Set stores entry for key, evicting the least recently used entry when full.
+func (m *MemoryCache) Set(key string, entry *CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		el.Value.(*memoryCacheItem).entry = entry
+		m.order.MoveToFront(el)
+		return
+	}
+	m.entries[key] = m.order.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for m.order.Len() > m.maxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// DiskCache is a Cache storing entries as files in a directory, so they are shared
+// by subsequent runs. Expired entries are kept for conditional requests and replaced
+// when fetched again; use Prune to bound the age and size of the directory.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache returns a DiskCache in dir, creating the directory if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// DefaultCacheDir returns the default directory of DiskCache.
+func DefaultCacheDir() string {
+	return fsutil.CacheDir("pages")
+}
+
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.dir, name[:2], name+".json")
+}
+
+// Get returns entry for key. Unreadable entries are treated as missing.
+func (d *DiskCache) Get(key string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logrus.Debugf("failed to read cache entry: %v", err)
+		}
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logrus.Debugf("failed to parse cache entry: %v", err)
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set stores entry for key. Failures are logged, caching is best effort.
+func (d *DiskCache) Set(key string, entry *CacheEntry) {
+	if err := d.set(key, entry); err != nil {
+		logrus.Warnf("failed to write cache entry: %v", err)
+	}
+}
+
+func (d *DiskCache) set(key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0o644)
+}
+
+// Default limits of DiskCache.Prune used by the CLI.
+const (
+	DefaultDiskCacheMaxAge  = 30 * 24 * time.Hour
+	DefaultDiskCacheMaxSize = 256 << 20
+)
+
+// Prune removes entries written more than maxAge ago and then the oldest entries until
+// the entries take at most maxSize bytes. Zero maxAge or maxSize disables the limit.
+// It returns the number of removed entries.
+func (d *DiskCache) Prune(maxAge time.Duration, maxSize int64) (int, error) {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cacheFile
+	var total int64
+	err := filepath.WalkDir(d.dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, f := range files {
+		expired := maxAge > 0 && f.modTime.Before(cutoff)
+		if !expired && (maxSize <= 0 || total <= maxSize) {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		total -= f.size
+		removed++
+	}
+	return removed, nil
+}
+
+// tieredCache looks up entries in caches in order, copying entries found in later caches
+// to the earlier ones.
+type tieredCache []Cache
+
+// NewTieredCache returns a Cache combining caches, e.g. MemoryCache in front of DiskCache.
+func NewTieredCache(caches ...Cache) Cache {
+	return tieredCache(caches)
+}
+
+func (t tieredCache) Get(key string) (*CacheEntry, bool) {
+	for i, c := range t {
+		if entry, ok := c.Get(key); ok {
+			for _, prev := range t[:i] {
+				prev.Set(key, entry)
+			}
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+func (t tieredCache) Set(key string, entry *CacheEntry) {
+	for _, c := range t {
+		c.Set(key, entry)
+	}
+}
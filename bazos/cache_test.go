@@ -0,0 +1,194 @@
+package bazos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", &CacheEntry{Body: []byte("a")})
+	cache.Set("b", &CacheEntry{Body: []byte("b")})
+	// "a" becomes recently used, "b" is evicted
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected entry a")
+	}
+	cache.Set("c", &CacheEntry{Body: []byte("c")})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected entry b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if e, ok := cache.Get(key); !ok || string(e.Body) != key {
+			t.Errorf("expected entry %s, got %v", key, e)
+		}
+	}
+	if n := cache.Len(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache.Set("https://www.bazos.sk/search.php?hledat=x", &CacheEntry{
+		URL:     "https://www.bazos.sk/search.php?hledat=x",
+		Body:    []byte("<html></html>"),
+		ETag:    `"v1"`,
+		Fetched: fetched,
+	})
+
+	// entries are shared by instances using the same directory
+	other, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := other.Get("https://www.bazos.sk/search.php?hledat=x")
+	if !ok {
+		t.Fatal("expected cached entry")
+	}
+	if string(e.Body) != "<html></html>" || e.ETag != `"v1"` || !e.Fetched.Equal(fetched) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if _, ok := other.Get("https://www.bazos.sk/"); ok {
+		t.Error("expected no entry for other key")
+	}
+}
+
+func TestDiskCachePrune(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	keys := []string{"old", "older", "new", "newer"}
+	written := []time.Time{now.Add(-48 * time.Hour), now.Add(-72 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour)}
+	for i, key := range keys {
+		cache.Set(key, &CacheEntry{URL: key, Body: []byte("<html></html>")})
+		if err := os.Chtimes(cache.path(key), written[i], written[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(cache.path("newer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := cache.Prune(24*time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 expired entries removed, got %d", removed)
+	}
+	for _, key := range []string{"old", "older"} {
+		if _, ok := cache.Get(key); ok {
+			t.Errorf("expected expired entry %q removed", key)
+		}
+	}
+
+	// the size limit removes the oldest entries first
+	removed, err = cache.Prune(0, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 entry removed over size, got %d", removed)
+	}
+	if _, ok := cache.Get("new"); ok {
+		t.Error("expected oldest entry removed")
+	}
+	if _, ok := cache.Get("newer"); !ok {
+		t.Error("expected newest entry kept")
+	}
+}
+
+func TestTieredCache(t *testing.T) {
+	front, back := NewMemoryCache(0), NewMemoryCache(0)
+	back.Set("a", &CacheEntry{Body: []byte("a")})
+
+	cache := NewTieredCache(front, back)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected entry from back cache")
+	}
+	if _, ok := front.Get("a"); !ok {
+		t.Error("expected entry copied to front cache")
+	}
+	cache.Set("b", &CacheEntry{Body: []byte("b")})
+	if _, ok := back.Get("b"); !ok {
+		t.Error("expected entry stored in back cache")
+	}
+}
+
+func TestFetchCache(t *testing.T) {
+	var calls, conditional int32
+	srv, client := newStubClient(t, CrawlPolicy{}, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "page")
+	})
+	cache := NewMemoryCache(0)
+	WithCache(cache, CacheTTL{Listing: time.Hour})(client)
+	u := srv.URL + "/search.php?hledat=x"
+
+	for i := 0; i < 2; i++ {
+		body, _, err := client.fetch(context.Background(), u, pageListing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "page" {
+			t.Errorf("unexpected body: %q", body)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 request, got %d", calls)
+	}
+
+	// expired entry is revalidated
+	e, _ := cache.Get(u)
+	expired := *e
+	expired.Fetched = time.Now().Add(-2 * time.Hour)
+	cache.Set(u, &expired)
+	body, _, err := client.fetch(context.Background(), u, pageListing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "page" {
+		t.Errorf("unexpected body of revalidated page: %q", body)
+	}
+	if conditional != 1 {
+		t.Errorf("expected 1 conditional request, got %d", conditional)
+	}
+	if e, _ := cache.Get(u); time.Since(e.Fetched) > time.Minute {
+		t.Errorf("expected refreshed entry, fetched %v", e.Fetched)
+	}
+
+	// phone pages and page types without TTL are not cached
+	for _, kind := range []pageKind{pagePhone, pageAd} {
+		if _, _, err := client.fetch(context.Background(), srv.URL+"/other", kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := cache.Get(srv.URL + "/other"); ok {
+		t.Error("expected uncached page")
+	}
+
+	want := CacheStats{Hits: 1, Revalidated: 1, Misses: 1}
+	if got := client.CacheStats(); got != want {
+		t.Errorf("expected stats %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,314 @@
+package bazos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+
+	"go.fabry.dev/fbot/internal/fsutil"
+)
+
+// Category is a category within a section, used as AdSection.Category.
+type Category struct {
+	Slug string
+	Name string
+	// Count is the number of ads in the category as displayed by the site, zero if unknown.
+	Count int `json:",omitempty" yaml:",omitempty"`
+}
+
+// CatalogSection is a section with its categories.
+type CatalogSection struct {
+	Slug       string
+	Name       string
+	Categories []Category `json:",omitempty" yaml:",omitempty"`
+}
+
+// Category returns the category with slug.
+func (s *CatalogSection) Category(slug string) (*Category, bool) {
+	for i := range s.Categories {
+		if s.Categories[i].Slug == slug {
+			return &s.Categories[i], true
+		}
+	}
+	return nil, false
+}
+
+// Catalog is the category tree of a site.
+type Catalog struct {
+	// Site is the site code.
+	Site string
+	// Updated is the time the catalog was discovered.
+	Updated  time.Time
+	Sections []CatalogSection
+}
+
+// Section returns the section with slug.
+func (c *Catalog) Section(slug string) (*CatalogSection, bool) {
+	for i := range c.Sections {
+		if c.Sections[i].Slug == slug {
+			return &c.Sections[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetSection adds or replaces the section.
+func (c *Catalog) SetSection(section CatalogSection) {
+	if s, ok := c.Section(section.Slug); ok {
+		*s = section
+		return
+	}
+	c.Sections = append(c.Sections, section)
+}
+
+// ValidateSection checks that the section and its category exist in the catalog.
+func (c *Catalog) ValidateSection(s *AdSection) error {
+	if s == nil || s.Section == "" || s.Section == AnySection {
+		return nil
+	}
+	section, ok := c.Section(s.Section)
+	if !ok {
+		return fmt.Errorf("unknown section %q", s.Section)
+	}
+	if s.Category == "" {
+		return nil
+	}
+	if _, ok := section.Category(s.Category); !ok {
+		slugs := make([]string, len(section.Categories))
+		for i, cat := range section.Categories {
+			slugs[i] = cat.Slug
+		}
+		return fmt.Errorf("unknown category %q in section %q, available categories: %s",
+			s.Category, s.Section, strings.Join(slugs, ", "))
+	}
+	return nil
+}
+
+// ValidateCategory checks that the category of q exists in its section of catalog.
+// Queries without category are not checked.
+func ValidateCategory(q SearchQuery, catalog *Catalog) error {
+	if q.Section == nil || q.Section.Category == "" {
+		return nil
+	}
+	return catalog.ValidateSection(q.Section)
+}
+
+// DefaultCatalogPath returns the default path of the cached catalog of site.
+func DefaultCatalogPath(site *Site) string {
+	return fsutil.CacheDir("catalog-" + site.Code + ".json")
+}
+
+// LoadCatalog loads catalog saved at path.
+func LoadCatalog(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the catalog to path, creating its directory.
+func (c *Catalog) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0o644)
+}
+
+// categoryCache caches discovered categories per section host.
+type categoryCache struct {
+	mu         sync.Mutex
+	categories map[string][]Category
+}
+
+func newCategoryCache() *categoryCache {
+	return &categoryCache{categories: make(map[string][]Category)}
+}
+
+// Categories returns categories of the section discovered from the section page.
+// Results are cached for the lifetime of the client.
+func (c *Client) Categories(ctx context.Context, section string) ([]Category, error) {
+	if !c.site.HasSection(section) || section == AnySection {
+		return nil, fmt.Errorf("unknown section %q on %s", section, c.site.Domain)
+	}
+	host := section + "." + c.domain
+
+	c.categories.mu.Lock()
+	cats, ok := c.categories.categories[host]
+	c.categories.mu.Unlock()
+	if ok {
+		return cats, nil
+	}
+
+	u := c.scheme + "://" + host + "/"
+	logrus.Debugf("fetching categories from url: %v", u)
+	body, pageUrl, err := c.fetch(ctx, u, pageSection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch section %s: %w", section, err)
+	}
+	if err := checkBlocked(pageUrl, body, c.selectorsFor(c.site).Listings.Info); err != nil {
+		return nil, err
+	}
+	cats, err = parseCategories(strings.NewReader(string(body)), pageUrl, c.selectorsFor(c.site))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse categories of section %s: %w", section, err)
+	}
+
+	c.categories.mu.Lock()
+	c.categories.categories[host] = cats
+	c.categories.mu.Unlock()
+	return cats, nil
+}
+
+// CatalogSection returns the section with its discovered categories.
+func (c *Client) CatalogSection(ctx context.Context, section string) (CatalogSection, error) {
+	cats, err := c.Categories(ctx, section)
+	if err != nil {
+		return CatalogSection{}, err
+	}
+	return CatalogSection{
+		Slug:       section,
+		Name:       c.site.SectionName(section),
+		Categories: cats,
+	}, nil
+}
+
+// ValidateQuery validates q with SearchQuery.Validate for the site of the client and its
+// category with ValidateCategory against the discovered categories of the section.
+// Categories that cannot be discovered are not checked, so searching is not blocked
+// by an unavailable section page.
+func (c *Client) ValidateQuery(ctx context.Context, q SearchQuery) error {
+	if err := q.Validate(c.site); err != nil {
+		return err
+	}
+	if q.Section == nil || q.Section.Category == "" || (q.Site != "" && q.Site != c.site.Code) {
+		return nil
+	}
+	section, err := c.CatalogSection(ctx, q.Section.Section)
+	if err != nil {
+		logrus.Warnf("cannot validate category: %v", err)
+		return nil
+	}
+	return ValidateCategory(q, &Catalog{Site: c.site.Code, Sections: []CatalogSection{section}})
+}
+
+// Catalog discovers categories of all sections of the site.
+func (c *Client) Catalog(ctx context.Context) (*Catalog, error) {
+	catalog := &Catalog{Site: c.site.Code, Updated: time.Now()}
+	for _, section := range c.site.Sections {
+		if section == AnySection {
+			continue
+		}
+		s, err := c.CatalogSection(ctx, section)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Sections = append(catalog.Sections, s)
+	}
+	return catalog, nil
+}
+
+// parseCategories parses category links of the section page at pageUrl.
+// Categories are links to single path segment on the section host, preferably from the left menu.
+func parseCategories(r io.Reader, pageUrl string, sel *Selectors) ([]Category, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	links := doc.Find(sel.Catalog.Links)
+	if links.Length() == 0 {
+		links = doc.Find("a[href]")
+	}
+
+	var cats []Category
+	seen := make(map[string]bool)
+	links.Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		u, err := base.Parse(href)
+		if err != nil || sectionOfHost(u.Host) != sectionOfHost(base.Host) || u.RawQuery != "" {
+			return
+		}
+		m := sel.categoryPathRegexp.FindStringSubmatch(u.Path)
+		if m == nil || seen[m[1]] {
+			return
+		}
+		slug := m[1]
+		if _, err := strconv.Atoi(slug); err == nil {
+			// pagination
+			return
+		}
+		seen[slug] = true
+
+		name := strings.TrimSpace(s.Text())
+		countText := name
+		if loc := sel.categoryCountRegexp.FindStringIndex(name); loc != nil {
+			name = strings.TrimSpace(name[:loc[0]])
+		} else {
+			countText = followingText(s)
+		}
+		cat := Category{Slug: slug, Name: name}
+		if m := sel.categoryCountRegexp.FindStringSubmatch(countText); m != nil {
+			cat.Count, _ = strconv.Atoi(strings.Map(func(r rune) rune {
+				if r < '0' || r > '9' {
+					return -1
+				}
+				return r
+			}, m[1]))
+		}
+		cats = append(cats, cat)
+	})
+	if len(cats) == 0 {
+		return nil, &LayoutError{URL: pageUrl, Diagnostics: Diagnostics{Missing: []string{"categories"}}}
+	}
+	return cats, nil
+}
+
+// followingText returns text directly following the link up to the next link or line break,
+// e.g. the count in "<a>Audio</a> <span>(123)</span>".
+func followingText(link *goquery.Selection) string {
+	var text strings.Builder
+	after := false
+	link.Parent().Contents().EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !after {
+			after = s.Get(0) == link.Get(0)
+			return true
+		}
+		switch goquery.NodeName(s) {
+		case "a", "br", "div":
+			return false
+		}
+		text.WriteString(s.Text())
+		return true
+	})
+	return text.String()
+}
+
+// sectionOfHost returns the section subdomain of host, e.g. "elektro" for "elektro.bazos.sk".
+func sectionOfHost(host string) string {
+	section, _, _ := strings.Cut(host, ".")
+	return section
+}
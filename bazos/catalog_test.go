@@ -0,0 +1,123 @@
+package bazos
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClientCategories(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("elektro."+testDomain+"/", "section_elektro.html")
+
+	cats, err := client.Categories(context.Background(), "elektro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Category{
+		{Slug: "audio", Name: "Audio", Count: 4231},
+		{Slug: "chladnicky", Name: "Chladničky, mrazničky", Count: 1872},
+		{Slug: "pracky", Name: "Práčky, sušičky", Count: 2015},
+		{Slug: "televizory", Name: "Televízory", Count: 3120},
+		{Slug: "ostatne", Name: "Ostatné"},
+	}
+	if !reflect.DeepEqual(cats, want) {
+		t.Errorf("unexpected categories:\n got: %+v\nwant: %+v", cats, want)
+	}
+
+	// cached
+	if _, err := client.Categories(context.Background(), "elektro"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+
+	if _, err := client.Categories(context.Background(), "nope"); err == nil {
+		t.Error("expected error for unknown section")
+	}
+}
+
+func TestCatalogValidateSection(t *testing.T) {
+	catalog := &Catalog{Site: "sk"}
+	catalog.SetSection(CatalogSection{Slug: "elektro", Categories: []Category{{Slug: "pracky"}}})
+
+	tests := []struct {
+		section *AdSection
+		wantErr bool
+	}{
+		{nil, false},
+		{&AdSection{Section: AnySection}, false},
+		{&AdSection{Section: "elektro"}, false},
+		{&AdSection{Section: "elektro", Category: "pracky"}, false},
+		{&AdSection{Section: "elektro", Category: "pracka"}, true},
+		{&AdSection{Section: "auto"}, true},
+	}
+	for _, test := range tests {
+		if err := catalog.ValidateSection(test.section); (err != nil) != test.wantErr {
+			t.Errorf("ValidateSection(%+v) error = %v, wantErr %v", test.section, err, test.wantErr)
+		}
+	}
+}
+
+func TestValidateCategory(t *testing.T) {
+	catalog := &Catalog{Site: "sk"}
+	catalog.SetSection(CatalogSection{Slug: "elektro", Categories: []Category{{Slug: "pracky"}}})
+
+	tests := []struct {
+		section *AdSection
+		wantErr bool
+	}{
+		{nil, false},
+		// sections are validated by SearchQuery.Validate
+		{&AdSection{Section: "auto"}, false},
+		{&AdSection{Section: "elektro", Category: "pracky"}, false},
+		{&AdSection{Section: "elektro", Category: "pracka"}, true},
+		{&AdSection{Section: "auto", Category: "pneumatiky"}, true},
+	}
+	for _, test := range tests {
+		if err := ValidateCategory(SearchQuery{Section: test.section}, catalog); (err != nil) != test.wantErr {
+			t.Errorf("ValidateCategory(%+v) error = %v, wantErr %v", test.section, err, test.wantErr)
+		}
+	}
+}
+
+func TestClientValidateQuery(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("elektro."+testDomain+"/", "section_elektro.html")
+
+	tests := []struct {
+		section *AdSection
+		wantErr bool
+	}{
+		{&AdSection{Section: "elektro", Category: "pracky"}, false},
+		{&AdSection{Section: "elektro", Category: "pracka"}, true},
+		{&AdSection{Section: "nope"}, true},
+		// categories of sections that cannot be discovered are not checked
+		{&AdSection{Section: "auto", Category: "pneumatiky"}, false},
+	}
+	for _, test := range tests {
+		q := SearchQuery{Query: "electrolux", Section: test.section}
+		if err := client.ValidateQuery(context.Background(), q); (err != nil) != test.wantErr {
+			t.Errorf("ValidateQuery(%+v) error = %v, wantErr %v", test.section, err, test.wantErr)
+		}
+	}
+}
+
+func TestCatalogSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog", "catalog-sk.json")
+	catalog := &Catalog{Site: "sk", Sections: []CatalogSection{
+		{Slug: "elektro", Name: "Elektro", Categories: []Category{{Slug: "pracky", Name: "Práčky", Count: 10}}},
+	}}
+	if err := catalog.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, catalog) {
+		t.Errorf("loaded catalog differs:\n got: %+v\nwant: %+v", loaded, catalog)
+	}
+}
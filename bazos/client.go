@@ -0,0 +1,545 @@
+package bazos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultScheme    = "https"
+	DefaultUserAgent = "fbot-bazos/1.0 (+https://go.fabry.dev/fbot)"
+)
+
+// DefaultClient is the Client used by the package-level functions.
+var DefaultClient = NewClient()
+
+// Client fetches and parses pages from bazos.
+type Client struct {
+	httpClient *http.Client
+	site       *Site
+	scheme     string
+	domain     string
+	userAgent  string
+	header     http.Header
+
+	revealPhone bool
+
+	policy  CrawlPolicy
+	limiter *hostLimiter
+	robots  *robotsCache
+
+	categories *categoryCache
+
+	selectorProfile *SelectorProfile
+
+	now func() time.Time
+
+	cache      Cache
+	cacheTTL   CacheTTL
+	cacheStats cacheCounters
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithSite sets the site used by the client. The base domain is set to the site domain
+// unless it is set explicitly using WithDomain.
+func WithSite(site *Site) ClientOption {
+	return func(c *Client) {
+		c.site = site
+	}
+}
+
+// WithDomain sets the base domain, e.g. "bazos.sk".
+func WithDomain(domain string) ClientOption {
+	return func(c *Client) {
+		c.domain = domain
+	}
+}
+
+// WithScheme sets the URL scheme used for requests, e.g. "http" for local test servers.
+func WithScheme(scheme string) ClientOption {
+	return func(c *Client) {
+		c.scheme = scheme
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with requests.
+func WithUserAgent(ua string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithHeader adds extra header sent with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithPhoneReveal enables fetching seller phone numbers using the site's reveal flow.
+func WithPhoneReveal(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.revealPhone = enabled
+	}
+}
+
+// WithCrawlPolicy sets the crawl policy controlling rate limiting, retries and robots.txt compliance.
+func WithCrawlPolicy(policy CrawlPolicy) ClientOption {
+	return func(c *Client) {
+		c.policy = policy
+	}
+}
+
+// WithCache enables caching of fetched pages in cache, pages are used for the TTL of their type
+// and revalidated using conditional requests afterwards. Caching is disabled by default.
+func WithCache(cache Cache, ttl CacheTTL) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+		c.cacheTTL = ttl
+	}
+}
+
+// WithSelectors sets the selectors used by the parsers, see LoadSelectorProfile.
+func WithSelectors(profile *SelectorProfile) ClientOption {
+	return func(c *Client) {
+		c.selectorProfile = profile
+	}
+}
+
+// WithClock sets the function returning the current time, used for dates relative to
+// the day of parsing, dates without year and date filters. It defaults to time.Now.
+func WithClock(now func() time.Time) ClientOption {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// NewClient returns a new Client configured with opts.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		site:       DefaultSite,
+		scheme:     DefaultScheme,
+		userAgent:  DefaultUserAgent,
+		header:     make(http.Header),
+		policy:     DefaultCrawlPolicy,
+		limiter:    newHostLimiter(),
+		robots:     newRobotsCache(),
+		categories: newCategoryCache(),
+
+		selectorProfile: DefaultSelectorProfile(),
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.domain == "" {
+		c.domain = c.site.Domain
+	}
+	return c
+}
+
+// Site returns the site of the client.
+func (c *Client) Site() *Site {
+	return c.site
+}
+
+// Domain returns the base domain of the client.
+func (c *Client) Domain() string {
+	return c.domain
+}
+
+// GetAd fetches and parses ad detail page at u.
+func (c *Client) GetAd(ctx context.Context, u string) (*Ad, error) {
+	logrus.Debugf("fetching ad from url: %v", u)
+
+	body, adUrl, err := c.fetch(ctx, u, pageAd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ad url: %w", err)
+	}
+	if adUrl != u {
+		logrus.Debugf("ad URL: %v", adUrl)
+	}
+	site := c.siteForURL(adUrl)
+	sel := c.selectorsFor(site)
+	if err := checkBlocked(adUrl, body, sel.Ad.Title); err != nil {
+		return nil, err
+	}
+
+	ad, err := parseAdListing(strings.NewReader(string(body)), site, sel, c.now())
+	if err != nil {
+		return nil, err
+	}
+	ad.Link = adUrl
+
+	if id, ok := adIDFromURL(adUrl); ok {
+		if ad.ID != "" && ad.ID != id {
+			ad.addParseError("ID", ad.ID, fmt.Sprintf("page ID does not match URL ID %s", id))
+		}
+		ad.ID = id
+	} else if ad.ID == "" {
+		ad.addParseError("ID", "", "ad ID not found")
+	}
+
+	ad.UserLink = resolveURL(adUrl, ad.UserLink)
+
+	if c.revealPhone && ad.phoneLink != "" {
+		phone, err := c.getPhoneNumber(ctx, resolveURL(adUrl, ad.phoneLink))
+		if err != nil {
+			logrus.Debugf("failed to reveal phone number: %v", err)
+			ad.addParseError("PhoneNumber", "", err)
+		} else {
+			ad.PhoneNumber = phone
+		}
+	}
+
+	for _, e := range ad.ParseErrors {
+		logrus.Debugf("ad %v: %v", ad.ID, e)
+	}
+	if diag := ad.Diagnostics(); diag.LayoutChanged() {
+		logrus.Warnf("ad %v: %s not found, the page layout may have changed", ad.ID, strings.Join(diag.Missing, ", "))
+	}
+
+	return ad, nil
+}
+
+func (c *Client) getPhoneNumber(ctx context.Context, u string) (string, error) {
+	logrus.Debugf("fetching phone number from url: %v", u)
+
+	body, _, err := c.fetch(ctx, u, pagePhone)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch phone number: %w", err)
+	}
+	return parsePhoneNumber(strings.NewReader(string(body)), c.selectorsFor(c.siteForURL(u)))
+}
+
+// GetAdById fetches detail of the ad with given id. The ad is fetched from its detail URL,
+// which the site redirects to the ad's section. Ads not available at the URL are looked up
+// in search results as a fallback, matching the ID exactly.
+func (c *Client) GetAdById(ctx context.Context, id string) (*Ad, error) {
+	if !adIDRegexp.MatchString(id) {
+		return nil, fmt.Errorf("invalid ad ID %q", id)
+	}
+	log := logrus.WithField("id", id)
+
+	log.Debugf("fetching ad by id")
+
+	ad, err := c.getAdByURL(ctx, id, c.adURL(id))
+	if !errors.Is(err, ErrAdNotFound) {
+		return ad, err
+	}
+
+	log.Debugf("ad not found at detail URL, searching: %v", err)
+	ads, err := c.Search(ctx, SearchQuery{
+		Query:    id,
+		Vicinity: DefaultVicinity,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("searching id %q error: %w", id, err)
+	}
+	for _, listed := range ads {
+		if listed.ID == id && listed.Link != "" {
+			log.Debugf("found ad by id in search results: %v", listed.Title)
+			return c.getAdByURL(ctx, id, listed.Link)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAdNotFound, id)
+}
+
+// adURL returns detail URL of the ad with id, the slug is not required by the site.
+func (c *Client) adURL(id string) string {
+	return c.scheme + "://www." + c.domain + "/" + c.site.AdPath + "/" + id + "/"
+}
+
+// getAdByURL fetches ad with id from u and verifies the site returned the ad.
+func (c *Client) getAdByURL(ctx context.Context, id, u string) (*Ad, error) {
+	ad, err := c.GetAd(ctx, u)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", ErrAdNotFound, id)
+		case http.StatusGone:
+			return nil, fmt.Errorf("%w: %s", ErrAdRemoved, id)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// removed ads are redirected away from the detail URL or show a notice instead of the ad
+	if linkID, ok := adIDFromURL(ad.Link); !ok || linkID != id {
+		return nil, fmt.Errorf("%w: %s redirected to %s", ErrAdRemoved, id, ad.Link)
+	}
+	if ad.Title == "" {
+		return nil, fmt.Errorf("%w: %s has no ad detail", ErrAdRemoved, id)
+	}
+	for _, e := range ad.ParseErrors {
+		if e.Field == "ID" && e.Value != "" {
+			// the page shows another ad than the URL
+			return nil, fmt.Errorf("%w: %s, got ad %s", ErrAdNotFound, id, e.Value)
+		}
+	}
+	return ad, nil
+}
+
+// Search returns ads matching the query from up to DefaultMaxPages pages.
+func (c *Client) Search(ctx context.Context, q SearchQuery) ([]Ad, error) {
+	logrus.Debugf("searching: %+v", q)
+
+	return collectAds(c.IterAds(ctx, q, ListOptions{}))
+}
+
+// ListAds fetches ad listings from u, following up to DefaultMaxPages next pages.
+func (c *Client) ListAds(ctx context.Context, u string) ([]Ad, error) {
+	return collectAds(c.IterListings(ctx, u, ListOptions{}))
+}
+
+func collectAds(it *AdIterator) ([]Ad, error) {
+	ads, err := it.All()
+	if err != nil {
+		return nil, err
+	}
+	if it.Truncated() {
+		logrus.Warnf("ad listings truncated after %d pages (%d ads)", it.Pages(), len(ads))
+	}
+	return ads, nil
+}
+
+func (c *Client) getAdListingsPage(ctx context.Context, u string) (*AdListingsPage, error) {
+	logrus.Debugf("fetching ad listings page from url: %v", u)
+
+	body, pageUrl, err := c.fetch(ctx, u, pageListing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	if err := checkBlocked(pageUrl, body, c.selectorsFor(c.siteForURL(pageUrl)).Listings.Info); err != nil {
+		return nil, err
+	}
+
+	return c.parseAdListingsPage(u, body)
+}
+
+// parseAdListingsPage parses ad listings page fetched from u, resolving ad links.
+func (c *Client) parseAdListingsPage(u string, body []byte) (*AdListingsPage, error) {
+	logrus.Tracef("parsing ad listing page body:\n%s", color.Gray.Sprintf("%s", body))
+
+	// Parse the ad listings
+	site := c.siteForURL(u)
+	adListingsPage, err := parseAdListingsPage(strings.NewReader(string(body)), site, c.selectorsFor(site), c.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ad listings page: %w", err)
+	}
+	if diag := adListingsPage.Diagnostics; diag.LayoutChanged() {
+		if len(adListingsPage.AdListings) == 0 {
+			return nil, &LayoutError{URL: u, Diagnostics: diag}
+		}
+		logrus.Warnf("ad listings page %s: %s not found, the page layout may have changed", u, strings.Join(diag.Missing, ", "))
+	}
+
+	for i := range adListingsPage.AdListings {
+		ad := &adListingsPage.AdListings[i]
+		ad.Link = resolveURL(u, ad.Link)
+	}
+
+	logrus.Tracef("ad listings page %d, From: %d, To: %d, Total: %d, Next: %s",
+		adListingsPage.Page, adListingsPage.From, adListingsPage.To, adListingsPage.Total, adListingsPage.NextPage)
+
+	return adListingsPage, nil
+}
+
+// searchSite returns site and domain used for the query.
+func (c *Client) searchSite(q SearchQuery) (*Site, string, error) {
+	if q.Site == "" {
+		return c.site, c.domain, nil
+	}
+	site, err := LookupSite(q.Site)
+	if err != nil {
+		return nil, "", err
+	}
+	if site == c.site {
+		return c.site, c.domain, nil
+	}
+	return site, site.Domain, nil
+}
+
+// AdSite returns the site the ad is listed on, matching the host of its link and falling
+// back to the client site.
+func (c *Client) AdSite(ad *Ad) *Site {
+	return c.siteForURL(ad.Link)
+}
+
+// siteForURL returns site matching the host of u, falling back to the client site.
+func (c *Client) siteForURL(u string) *Site {
+	if parsed, err := url.Parse(u); err == nil {
+		if site, ok := siteForHost(parsed.Hostname()); ok {
+			return site
+		}
+	}
+	return c.site
+}
+
+// fetch performs GET request for u following the crawl policy and returns response body
+// with the final URL after redirects. Pages of kind are served from the client cache
+// while fresh and revalidated when expired.
+func (c *Client) fetch(ctx context.Context, u string, kind pageKind) ([]byte, string, error) {
+	ttl := time.Duration(0)
+	if c.cache != nil {
+		ttl = c.cacheTTL.forKind(kind)
+	}
+	var cached *CacheEntry
+	if ttl > 0 {
+		if entry, ok := c.cache.Get(u); ok {
+			if time.Since(entry.Fetched) < ttl {
+				logrus.Debugf("cache hit: %v", u)
+				c.cacheStats.hits.Add(1)
+				return entry.Body, entry.URL, nil
+			}
+			if entry.ETag != "" || entry.LastModified != "" {
+				cached = entry
+			}
+		}
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, "", err
+	}
+	if c.policy.RespectRobots {
+		if err := c.checkRobots(ctx, parsed); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx, parsed.Host, c.crawlInterval(parsed.Host)); err != nil {
+			return nil, "", err
+		}
+		resp, err := c.doFetch(ctx, u, cached)
+		if err == nil {
+			if ttl > 0 {
+				entry := c.storeResponse(u, resp, cached)
+				return entry.Body, entry.URL, nil
+			}
+			return resp.Body, resp.URL, nil
+		}
+		delay, retry := c.policy.retryDelay(ctx, attempt, err)
+		if !retry {
+			return nil, "", err
+		}
+		logrus.Debugf("request failed (attempt %d/%d), retrying in %v: %v", attempt+1, c.policy.MaxRetries+1, delay, err)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// storeResponse stores response for u in the cache and returns the entry to use. Not modified
+// response refreshes the cached entry.
+func (c *Client) storeResponse(u string, resp *fetchResponse, cached *CacheEntry) *CacheEntry {
+	entry := &resp.CacheEntry
+	if resp.notModified {
+		logrus.Debugf("cache revalidated: %v", u)
+		c.cacheStats.revalidated.Add(1)
+		// entries may be shared by caches, keep the cached one intact
+		refreshed := *cached
+		refreshed.Fetched = entry.Fetched
+		if entry.ETag != "" {
+			refreshed.ETag = entry.ETag
+		}
+		if entry.LastModified != "" {
+			refreshed.LastModified = entry.LastModified
+		}
+		entry = &refreshed
+	} else {
+		c.cacheStats.misses.Add(1)
+	}
+	c.cache.Set(u, entry)
+	return entry
+}
+
+// fetchResponse is a successful response of doFetch.
+type fetchResponse struct {
+	CacheEntry
+	// notModified is set for response to conditional request confirming the cached entry,
+	// the body is empty then.
+	notModified bool
+}
+
+// doFetch performs single GET request for u, conditional if cached is not nil.
+func (c *Client) doFetch(ctx context.Context, u string, cached *CacheEntry) (*fetchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	logrus.Debugf("response status: %v", resp.Status)
+
+	result := &fetchResponse{CacheEntry: CacheEntry{
+		URL:          resp.Request.URL.String(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		result.URL = cached.URL
+		result.notModified = true
+		return result, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &StatusError{
+			URL:        resp.Request.URL.String(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+		statusErr.RetryAfter, statusErr.hasRetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, statusErr
+	}
+
+	result.Body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	return result, nil
+}
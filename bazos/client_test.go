@@ -0,0 +1,321 @@
+package bazos
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.fabry.dev/fbot/bazos/internal/bazostest"
+)
+
+const testDomain = "bazos.test"
+
+func newTestServer(t *testing.T, opts ...ClientOption) (*bazostest.Server, *Client) {
+	t.Helper()
+	srv := bazostest.NewServer(t, "testdata")
+	client := NewClient(append([]ClientOption{
+		WithHTTPClient(srv.HTTPClient()),
+		WithScheme("http"),
+		WithDomain(testDomain),
+		WithCrawlPolicy(CrawlPolicy{}),
+		WithClock(func() time.Time { return testNow }),
+	}, opts...)...)
+	return srv, client
+}
+
+func TestClientListAdsPagination(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php?hledat=electrolux&rubriky=elektro&crz=3", "search_electrolux_3.html")
+	srv.Handle("/search.php", "search_electrolux.html")
+
+	ads, err := client.ListAds(context.Background(), "http://www."+testDomain+"/search.php?hledat=electrolux&rubriky=elektro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ads) != 5 {
+		t.Fatalf("expected 5 ads, got %d", len(ads))
+	}
+	wantIDs := []string{"151234567", "151111111", "150999999", "150888888", "150777777"}
+	for i, ad := range ads {
+		if ad.ID != wantIDs[i] {
+			t.Errorf("ad #%d: expected ID %q, got %q", i, wantIDs[i], ad.ID)
+		}
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestClientSearch(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("elektro."+testDomain+"/pracky/", "search_electrolux_3.html")
+
+	ads, err := client.Search(context.Background(), SearchQuery{
+		Query:    "electrolux",
+		Vicinity: 25,
+		Section: &AdSection{
+			Category: "pracky",
+			Section:  "elektro",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ads) != 2 {
+		t.Fatalf("expected 2 ads, got %d", len(ads))
+	}
+	req := srv.Requests()[0]
+	if got := req.URL.Query().Get("hledat"); got != "electrolux" {
+		t.Errorf("expected query %q, got %q", "electrolux", got)
+	}
+	if got := req.Header.Get("User-Agent"); got != DefaultUserAgent {
+		t.Errorf("expected user agent %q, got %q", DefaultUserAgent, got)
+	}
+}
+
+func TestClientSearchEmpty(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_empty.html")
+
+	ads, err := client.Search(context.Background(), SearchQuery{Query: "nonexistent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ads) != 0 {
+		t.Fatalf("expected no ads, got %d", len(ads))
+	}
+}
+
+func TestClientGetAd(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/inzerat/150722248/predam-nosic-bicyklov-na-tazne.php", "ad_detail.html")
+
+	const adUrl = "http://auto." + testDomain + "/inzerat/150722248/predam-nosic-bicyklov-na-tazne.php"
+	ad, err := client.GetAd(context.Background(), adUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.Link != adUrl {
+		t.Errorf("expected link %q, got %q", adUrl, ad.Link)
+	}
+	if ad.Title != "Predám nosič bicyklov na ťažné" {
+		t.Errorf("unexpected title: %q", ad.Title)
+	}
+	if ad.ID != "150722248" {
+		t.Errorf("expected ID %q, got %q", "150722248", ad.ID)
+	}
+	if ad.Location != "Bratislava" || ad.PostCode != "811 01" {
+		t.Errorf("unexpected location: %q %q", ad.PostCode, ad.Location)
+	}
+	if ad.UserName != "Peter" {
+		t.Errorf("unexpected user name: %q", ad.UserName)
+	}
+	if want := "https://www.bazos.sk/hodnotenie.php?idmail=1234567&jmeno=Peter"; ad.UserLink != want {
+		t.Errorf("expected user link %q, got %q", want, ad.UserLink)
+	}
+	if ad.PhoneNumber != "" {
+		t.Errorf("expected no phone number without reveal, got %q", ad.PhoneNumber)
+	}
+	if len(ad.ParseErrors) > 0 {
+		t.Errorf("unexpected parse errors: %v", ad.ParseErrors)
+	}
+}
+
+func TestClientGetAdPhoneReveal(t *testing.T) {
+	srv, client := newTestServer(t, WithPhoneReveal(true))
+	srv.Handle("/inzerat/150722248/predam-nosic-bicyklov-na-tazne.php", "ad_detail.html")
+	srv.Handle("/detailtel.php", "detailtel.html")
+
+	ad, err := client.GetAd(context.Background(), "http://auto."+testDomain+"/inzerat/150722248/predam-nosic-bicyklov-na-tazne.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.PhoneNumber != "0905123456" {
+		t.Errorf("expected phone number %q, got %q", "0905123456", ad.PhoneNumber)
+	}
+}
+
+func TestClientGetAdMissingFields(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/inzerat/150722248/x.php", "search_empty.html")
+
+	ad, err := client.GetAd(context.Background(), "http://auto."+testDomain+"/inzerat/150722248/x.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.ID != "150722248" {
+		t.Errorf("expected ID from URL, got %q", ad.ID)
+	}
+	failed := make(map[string]bool)
+	for _, e := range ad.ParseErrors {
+		failed[e.Field] = true
+	}
+	for _, field := range []string{"Title", "Date", "UserName", "Location", "PostCode", "Views", "Price"} {
+		if !failed[field] {
+			t.Errorf("expected parse error for field %s", field)
+		}
+	}
+}
+
+func TestClientGetAdById(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/inzerat/150722248/", "ad_detail.html")
+
+	ad, err := client.GetAdById(context.Background(), "150722248")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.ID != "150722248" || ad.Title != "Predám nosič bicyklov na ťažné" {
+		t.Errorf("unexpected ad %s: %q", ad.ID, ad.Title)
+	}
+	// detail fields are not available in listings
+	if ad.UserName == "" {
+		t.Error("expected seller name from detail page")
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestClientGetAdByIdSites(t *testing.T) {
+	tests := []struct {
+		site    *Site
+		path    string
+		fixture string
+		title   string
+	}{
+		{SitePL, "/ogloszenie/412345678/", "ad_detail_pl.html", "Rower górski Kross Hexagon"},
+		{SiteAT, "/anzeige/98765432/", "ad_detail_at.html", "City Fahrrad KTM"},
+	}
+	for _, test := range tests {
+		t.Run(test.site.Code, func(t *testing.T) {
+			srv, client := newTestServer(t, WithSite(test.site))
+			srv.Handle(test.path, test.fixture)
+
+			id := strings.Split(test.path, "/")[2]
+			ad, err := client.GetAdById(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ad.ID != id || ad.Title != test.title {
+				t.Errorf("unexpected ad %s: %q", ad.ID, ad.Title)
+			}
+		})
+	}
+}
+
+func TestClientGetAdByIdErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		routes map[string]string
+		want   error
+	}{
+		{
+			name:   "not listed",
+			id:     "150722248",
+			routes: map[string]string{"/search.php": "search_electrolux.html"},
+			want:   ErrAdNotFound,
+		},
+		{
+			name: "other ad",
+			id:   "151111111",
+			routes: map[string]string{
+				"/inzerat/151111111/": "ad_detail.html",
+				"/search.php":         "search_electrolux.html",
+				"/inzerat/151111111/darujem-staru-pracku.php": "ad_detail.html",
+			},
+			want: ErrAdNotFound,
+		},
+		{
+			name:   "removed",
+			id:     "150722248",
+			routes: map[string]string{"/inzerat/150722248/": "search_empty.html"},
+			want:   ErrAdRemoved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, client := newTestServer(t)
+			for route, file := range tt.routes {
+				srv.Handle(route, file)
+			}
+			_, err := client.GetAdById(context.Background(), tt.id)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	_, client := newTestServer(t)
+	if _, err := client.GetAdById(context.Background(), "abc"); err == nil {
+		t.Error("expected error for invalid ID")
+	}
+}
+
+func TestClientContextCanceled(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_electrolux.html")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Search(ctx, SearchQuery{Query: "electrolux"}); err == nil {
+		t.Fatal("expected error for canceled context")
+	}
+}
+
+func TestClientSearchSite(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_cz_skoda.html")
+
+	ads, err := client.Search(context.Background(), SearchQuery{Query: "skoda", Site: "cz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := srv.Requests()[0].Host; host != SiteCZ.Domain {
+		t.Errorf("expected request to %s, got %s", SiteCZ.Domain, host)
+	}
+	if len(ads) != 2 {
+		t.Fatalf("expected 2 ads, got %d", len(ads))
+	}
+	for _, ad := range ads {
+		if ad.Price.Currency != "CZK" {
+			t.Errorf("expected currency CZK, got %q", ad.Price.Currency)
+		}
+	}
+	if ads[0].Price.Amount != 35000 {
+		t.Errorf("expected price 35000, got %v", ads[0].Price)
+	}
+}
+
+func TestLookupSite(t *testing.T) {
+	for _, code := range []string{"sk", "CZ", "bazos.pl", "at"} {
+		if _, err := LookupSite(code); err != nil {
+			t.Errorf("LookupSite(%q): %v", code, err)
+		}
+	}
+	if _, err := LookupSite("hu"); err == nil {
+		t.Error("expected error for unknown site")
+	}
+}
+
+func TestClientSearchPriceFilter(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_electrolux.html")
+	srv.Handle("/search.php?hledat=electrolux&rubriky=elektro&crz=3", "search_electrolux_3.html")
+
+	ads, err := client.Search(context.Background(), SearchQuery{Query: "electrolux", PriceFrom: 100, PriceTo: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, ad := range ads {
+		ids = append(ids, ad.ID)
+	}
+	// fixed 150 and in-text 220 are in range, free and 1250 are not
+	if want := "151234567,150999999"; strings.Join(ids, ",") != want {
+		t.Errorf("expected ads %s, got %s", want, strings.Join(ids, ","))
+	}
+}
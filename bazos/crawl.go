@@ -0,0 +1,321 @@
+package bazos
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CrawlPolicy controls pacing and retrying of requests made by the client.
+type CrawlPolicy struct {
+	// MinInterval is the minimum delay between requests to the same host.
+	MinInterval time.Duration
+	// MaxRetries is the number of retries for failed requests.
+	MaxRetries int
+	// BaseBackoff is the initial delay before retry, doubled with each attempt.
+	BaseBackoff time.Duration
+	// MaxBackoff is the maximum delay before retry. Responses asking to retry
+	// after a longer delay via Retry-After are not retried.
+	MaxBackoff time.Duration
+	// RespectRobots enables checking robots.txt rules before requests.
+	RespectRobots bool
+}
+
+// DefaultCrawlPolicy is the crawl policy used by default.
+var DefaultCrawlPolicy = CrawlPolicy{
+	MinInterval: time.Second,
+	MaxRetries:  3,
+	BaseBackoff: time.Second,
+	MaxBackoff:  30 * time.Second,
+}
+
+// backoff returns delay before retry attempt (starting from 0).
+func (p CrawlPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseBackoff << attempt
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// equal jitter: [d/2, d)
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// retryDelay returns delay before retrying request that failed with err and whether to retry.
+func (p CrawlPolicy) retryDelay(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if !statusErr.Temporary() {
+			return 0, false
+		}
+		if statusErr.hasRetryAfter {
+			if p.MaxBackoff > 0 && statusErr.RetryAfter > p.MaxBackoff {
+				return 0, false
+			}
+			return statusErr.RetryAfter, true
+		}
+		return p.backoff(attempt), true
+	}
+	if isTransientNetError(err) {
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// isTransientNetError reports whether err is a network error that may not repeat: a timeout,
+// a reset or refused connection, or a connection closed before the response was read.
+// Other errors, e.g. of TLS or unsupported scheme, fail the same way when retried.
+func isTransientNetError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// parseRetryAfter parses Retry-After header value in seconds or HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// hostLimiter spaces requests to the same host.
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{next: make(map[string]time.Time)}
+}
+
+// wait blocks until request to host is allowed.
+func (l *hostLimiter) wait(ctx context.Context, host string, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	t := l.next[host]
+	if t.Before(now) {
+		t = now
+	}
+	l.next[host] = t.Add(interval)
+	l.mu.Unlock()
+
+	return sleepCtx(ctx, time.Until(t))
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// robotsRules are the rules of robots.txt applicable to the client.
+type robotsRules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// allowed reports whether path is allowed, the longest matching rule wins.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	best, allowed := -1, true
+	for _, p := range r.disallow {
+		if p != "" && strings.HasPrefix(path, p) && len(p) > best {
+			best, allowed = len(p), false
+		}
+	}
+	for _, p := range r.allow {
+		if strings.HasPrefix(path, p) && len(p) >= best {
+			best, allowed = len(p), true
+		}
+	}
+	return allowed
+}
+
+// parseRobots parses robots.txt rules for the user agent.
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	agent := strings.ToLower(userAgent)
+	if i := strings.IndexAny(agent, "/ "); i >= 0 {
+		agent = agent[:i]
+	}
+
+	var (
+		specific, generic *robotsRules
+		current           []*robotsRules
+		inAgents          bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if !inAgents {
+				current = nil
+			}
+			inAgents = true
+			ua := strings.ToLower(value)
+			switch {
+			case ua == "*":
+				if generic == nil {
+					generic = &robotsRules{}
+				}
+				current = append(current, generic)
+			case agent != "" && strings.Contains(agent, ua):
+				if specific == nil {
+					specific = &robotsRules{}
+				}
+				current = append(current, specific)
+			}
+			continue
+		}
+		inAgents = false
+		for _, rules := range current {
+			switch key {
+			case "allow":
+				rules.allow = append(rules.allow, value)
+			case "disallow":
+				rules.disallow = append(rules.disallow, value)
+			case "crawl-delay":
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					rules.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+	if specific != nil {
+		return specific
+	}
+	return generic
+}
+
+// robotsCache caches robots.txt rules per host.
+type robotsCache struct {
+	mu    sync.Mutex
+	rules map[string]*robotsRules
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{rules: make(map[string]*robotsRules)}
+}
+
+// checkRobots returns error if request for u is disallowed by robots.txt.
+func (c *Client) checkRobots(ctx context.Context, u *url.URL) error {
+	rules, err := c.robotsFor(ctx, u)
+	if err != nil {
+		return err
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !rules.allowed(path) {
+		return fmt.Errorf("%w: %s", ErrDisallowedByRobots, u)
+	}
+	return nil
+}
+
+func (c *Client) robotsFor(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	c.robots.mu.Lock()
+	rules, ok := c.robots.rules[u.Host]
+	c.robots.mu.Unlock()
+	if ok {
+		return rules, nil
+	}
+
+	robotsUrl := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+	logrus.Debugf("fetching robots.txt from url: %v", robotsUrl)
+
+	if err := c.limiter.wait(ctx, u.Host, c.policy.MinInterval); err != nil {
+		return nil, err
+	}
+	resp, err := c.doFetch(ctx, robotsUrl, nil)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.Temporary() {
+			// missing robots.txt allows everything
+			logrus.Debugf("robots.txt not available: %v", err)
+		} else {
+			return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+		}
+	} else {
+		rules = parseRobots(strings.NewReader(string(resp.Body)), c.userAgent)
+	}
+
+	c.robots.mu.Lock()
+	c.robots.rules[u.Host] = rules
+	c.robots.mu.Unlock()
+
+	return rules, nil
+}
+
+// crawlInterval returns minimal interval between requests to host.
+func (c *Client) crawlInterval(host string) time.Duration {
+	interval := c.policy.MinInterval
+	if c.policy.RespectRobots {
+		c.robots.mu.Lock()
+		rules := c.robots.rules[host]
+		c.robots.mu.Unlock()
+		if rules != nil && rules.crawlDelay > interval {
+			interval = rules.crawlDelay
+		}
+	}
+	return interval
+}
@@ -0,0 +1,288 @@
+package bazos
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newStubClient returns client sending requests to handler with given crawl policy.
+func newStubClient(t *testing.T, policy CrawlPolicy, handler http.HandlerFunc) (*httptest.Server, *Client) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client := NewClient(
+		WithHTTPClient(srv.Client()),
+		WithScheme("http"),
+		WithDomain(strings.TrimPrefix(srv.URL, "http://")),
+		WithCrawlPolicy(policy),
+	)
+	return srv, client
+}
+
+func TestFetchRetry(t *testing.T) {
+	var calls int32
+	srv, client := newStubClient(t, CrawlPolicy{MaxRetries: 3, BaseBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) <= 2 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		})
+
+	body, _, err := client.fetch(context.Background(), srv.URL+"/", pageListing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "ok" {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 requests, got %d", calls)
+	}
+}
+
+func TestFetchRetryExhausted(t *testing.T) {
+	var calls int32
+	srv, client := newStubClient(t, CrawlPolicy{MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			http.Error(w, "error", http.StatusBadGateway)
+		})
+
+	_, _, err := client.fetch(context.Background(), srv.URL+"/", pageListing)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, statusErr.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 requests, got %d", calls)
+	}
+}
+
+func TestFetchNotRetried(t *testing.T) {
+	var calls int32
+	srv, client := newStubClient(t, CrawlPolicy{MaxRetries: 3, BaseBackoff: time.Millisecond},
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			http.NotFound(w, r)
+		})
+
+	_, _, err := client.fetch(context.Background(), srv.URL+"/", pageListing)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 StatusError, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 request, got %d", calls)
+	}
+}
+
+func TestRetryDelayNetErrors(t *testing.T) {
+	policy := CrawlPolicy{MaxRetries: 1, BaseBackoff: time.Millisecond}
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://www.bazos.sk/", Err: err}
+	}
+	tests := []struct {
+		name  string
+		err   error
+		retry bool
+	}{
+		{"timeout", urlErr(&net.DNSError{Err: "i/o timeout", IsTimeout: true}), true},
+		{"reset", urlErr(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"refused", urlErr(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"unexpected EOF", urlErr(io.ErrUnexpectedEOF), true},
+		{"EOF", urlErr(io.EOF), true},
+		{"dns", urlErr(&net.DNSError{Err: "no such host", IsNotFound: true}), false},
+		{"tls", urlErr(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}), false},
+		{"scheme", urlErr(errors.New(`unsupported protocol scheme "ftp"`)), false},
+	}
+	for _, test := range tests {
+		if _, retry := policy.retryDelay(context.Background(), 0, test.err); retry != test.retry {
+			t.Errorf("%s: expected retry %v, got %v", test.name, test.retry, retry)
+		}
+	}
+}
+
+func TestFetchTLSErrorNotRetried(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	// the rejected certificate is logged by the server
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	// the default client does not trust the certificate of the test server
+	client := NewClient(
+		WithHTTPClient(&http.Client{}),
+		WithCrawlPolicy(CrawlPolicy{MaxRetries: 3, BaseBackoff: time.Millisecond}),
+	)
+
+	_, _, err := client.fetch(context.Background(), srv.URL+"/", pageListing)
+	if err == nil {
+		t.Fatal("expected certificate error")
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Errorf("expected 1 connection, got %d", n)
+	}
+
+	_, _, err = client.fetch(context.Background(), "ftp://www.bazos.sk/", pageListing)
+	if err == nil {
+		t.Fatal("expected unsupported scheme error")
+	}
+}
+
+func TestFetchRetryAfter(t *testing.T) {
+	var calls int32
+	srv, client := newStubClient(t, CrawlPolicy{MaxRetries: 3, BaseBackoff: time.Hour, MaxBackoff: 10 * time.Millisecond},
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&calls, 1)
+			switch {
+			case n == 1:
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+			case r.URL.Path == "/long":
+				w.Header().Set("Retry-After", "120")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+			default:
+				_, _ = w.Write([]byte("ok"))
+			}
+		})
+
+	// Retry-After: 0 is retried immediately instead of waiting for backoff
+	if _, _, err := client.fetch(context.Background(), srv.URL+"/", pageListing); err != nil {
+		t.Fatal(err)
+	}
+
+	// Retry-After longer than MaxBackoff is not retried
+	_, _, err := client.fetch(context.Background(), srv.URL+"/long", pageListing)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected StatusError, got %v", err)
+	}
+	if statusErr.RetryAfter != 2*time.Minute {
+		t.Errorf("expected RetryAfter 2m, got %v", statusErr.RetryAfter)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 requests, got %d", calls)
+	}
+}
+
+func TestFetchRateLimit(t *testing.T) {
+	srv, client := newStubClient(t, CrawlPolicy{MinInterval: 30 * time.Millisecond},
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, _, err := client.fetch(context.Background(), srv.URL+"/", pageListing); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("expected requests spaced by rate limit, took %v", elapsed)
+	}
+}
+
+func TestFetchRobots(t *testing.T) {
+	var robotsCalls int32
+	srv, client := newStubClient(t, CrawlPolicy{RespectRobots: true},
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				atomic.AddInt32(&robotsCalls, 1)
+				_, _ = w.Write([]byte("User-agent: *\nDisallow: /search.php\nAllow: /search.php?allowed\n"))
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		})
+
+	if _, _, err := client.fetch(context.Background(), srv.URL+"/inzerat/1/x.php", pageListing); err != nil {
+		t.Fatalf("expected allowed request, got %v", err)
+	}
+	if _, _, err := client.fetch(context.Background(), srv.URL+"/search.php?hledat=x", pageListing); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("expected ErrDisallowedByRobots, got %v", err)
+	}
+	if _, _, err := client.fetch(context.Background(), srv.URL+"/search.php?allowed", pageListing); err != nil {
+		t.Fatalf("expected allowed request, got %v", err)
+	}
+	if robotsCalls != 1 {
+		t.Errorf("expected robots.txt fetched once, got %d", robotsCalls)
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	const robots = `
+# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: fbot
+User-agent: otherbot
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /search.php
+`
+	rules := parseRobots(strings.NewReader(robots), DefaultUserAgent)
+	if rules.allowed("/private/x") {
+		t.Error("expected /private to be disallowed")
+	}
+	if !rules.allowed("/search.php") {
+		t.Error("expected specific group to override generic group")
+	}
+	if rules.crawlDelay != 2*time.Second {
+		t.Errorf("expected crawl delay 2s, got %v", rules.crawlDelay)
+	}
+
+	generic := parseRobots(strings.NewReader(robots), "somebot/1.0")
+	if generic.allowed("/search.php?hledat=x") {
+		t.Error("expected /search.php to be disallowed for generic agent")
+	}
+	if !generic.allowed("/private") {
+		t.Error("expected /private to be allowed for generic agent")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2024 12:01:00 GMT", time.Minute, true},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value, now)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", test.value, got, ok, test.want, test.wantOk)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package bazos
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TimeLocation returns location of the site time zone. If the time zone database
+// is not available, Central European Time without daylight saving is used.
+func (s *Site) TimeLocation() *time.Location {
+	s.locOnce.Do(func() {
+		loc, err := time.LoadLocation(s.TimeZone)
+		if err != nil {
+			logrus.Debugf("failed to load time zone %q: %v", s.TimeZone, err)
+			loc = time.FixedZone("CET", 60*60)
+		}
+		s.loc = loc
+	})
+	return s.loc
+}
+
+// Listed returns the date the ad was last listed, the bump date if the ad was bumped.
+func (ad Ad) Listed() time.Time {
+	if ad.Bumped != nil {
+		return *ad.Bumped
+	}
+	return ad.Date
+}
+
+var errNoDate = errors.New("no date found")
+
+// adDate is the parsed date text of an ad.
+type adDate struct {
+	posted time.Time
+	// bumped is the later date of texts with two dates, zero otherwise.
+	bumped time.Time
+	// top reports whether the text has the top marker.
+	top bool
+}
+
+// bumpedDate returns the bump date for Ad.Bumped, nil if the ad was not bumped.
+func (d adDate) bumpedDate() *time.Time {
+	if d.bumped.IsZero() {
+		return nil
+	}
+	return &d.bumped
+}
+
+// dateToken is a date found in a date text at position pos.
+type dateToken struct {
+	pos  int
+	date time.Time
+}
+
+// parseAdDate parses date text of an ad, e.g. "- [14.1. 2024]", "TOP - [14.1.]", "dnes" or
+// "[2.1. 2024] - [14.1. 2024]" of a bumped ad. Dates are in the site time zone, dates without
+// year are in the year before now if they would be in the future, e.g. "28.12." in January.
+func parseAdDate(text string, site *Site, sel *Selectors, now time.Time) (adDate, error) {
+	var d adDate
+	if marker := sel.Listings.TopMarker; marker != "" && strings.Contains(text, marker) {
+		d.top = true
+		text = strings.Replace(text, marker, "", 1)
+	}
+
+	loc := site.TimeLocation()
+	now = now.In(loc)
+	var tokens []dateToken
+	for _, m := range sel.dateRegexp.FindAllStringIndex(text, -1) {
+		date, err := parseDateToken(text[m[0]:m[1]], sel.DateLayout, loc, now)
+		if err != nil {
+			return d, err
+		}
+		tokens = append(tokens, dateToken{m[0], date})
+	}
+	lower := strings.ToLower(text)
+	for word, days := range site.RelativeDays {
+		if i := indexWord(lower, word); i >= 0 {
+			y, m, day := now.Date()
+			tokens = append(tokens, dateToken{i, time.Date(y, m, day-days, 0, 0, 0, 0, loc)})
+		}
+	}
+	if len(tokens) == 0 {
+		return d, errNoDate
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].pos < tokens[j].pos })
+	d.posted = tokens[0].date
+	if last := tokens[len(tokens)-1].date; last.After(d.posted) {
+		d.bumped = last
+	}
+	return d, nil
+}
+
+// parseDateToken parses date s with layout, or the layout without year. Spaces are ignored,
+// so "14.1.2024" and "14. 1. 2024" match layout "2.1. 2006". Dates without year are in the
+// latest year in which they are valid and not after now, e.g. "29.2." in the last leap year.
+func parseDateToken(s, layout string, loc *time.Location, now time.Time) (time.Time, error) {
+	s = strings.Join(strings.Fields(s), "")
+	layout = strings.Join(strings.Fields(layout), "")
+	if date, err := time.ParseInLocation(layout, s, loc); err == nil {
+		return date, nil
+	}
+	parsed, err := time.ParseInLocation(strings.Replace(layout, "2006", "", 1), s, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	_, month, day := parsed.Date()
+	for year := now.Year(); year >= now.Year()-maxYearlessAge; year-- {
+		date := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		if date.Day() == day && !date.After(now.AddDate(0, 0, 1)) {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// maxYearlessAge is the number of years searched back for a year of a date without year.
+const maxYearlessAge = 8
+
+// indexWord returns index of the first occurrence of word in s not being part of another word.
+func indexWord(s, word string) int {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], word)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		end := i + len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if (i == 0 || !unicode.IsLetter(before)) && (end == len(s) || !unicode.IsLetter(after)) {
+			return i
+		}
+		offset = end
+	}
+}
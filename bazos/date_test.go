@@ -0,0 +1,78 @@
+package bazos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAdDate(t *testing.T) {
+	loc := SiteSK.TimeLocation()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, loc)
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	tests := []struct {
+		text    string
+		posted  time.Time
+		bumped  time.Time
+		top     bool
+		wantErr bool
+	}{
+		{text: "- [14.1. 2024]", posted: day(2024, 1, 14)},
+		{text: "[14.1.2024]", posted: day(2024, 1, 14)},
+		{text: "14. 1. 2024", posted: day(2024, 1, 14)},
+		{text: "TOP - [14.1. 2024]", posted: day(2024, 1, 14), top: true},
+		{text: "[14.1.]", posted: day(2024, 1, 14)},
+		// dates without year after now are from the last year
+		{text: "[28.12.]", posted: day(2023, 12, 28)},
+		{text: "- [2.1. 2024] - [14.1. 2024]", posted: day(2024, 1, 2), bumped: day(2024, 1, 14)},
+		{text: "TOP - [28.12.] - [14.1.]", posted: day(2023, 12, 28), bumped: day(2024, 1, 14), top: true},
+		{text: "- dnes", posted: day(2024, 1, 15)},
+		{text: "[10.1. 2024] - Včera", posted: day(2024, 1, 10), bumped: day(2024, 1, 14)},
+		{text: "dnesok", wantErr: true},
+		{text: "", wantErr: true},
+		{text: "[31.2. 2024]", wantErr: true},
+	}
+	sel := DefaultSelectorProfile().ForSite(SiteSK)
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got, err := parseAdDate(test.text, SiteSK, sel, now)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error: %v, got: %v", test.wantErr, err)
+			}
+			if !got.posted.Equal(test.posted) || !got.bumped.Equal(test.bumped) || got.top != test.top {
+				t.Errorf("expected posted %v, bumped %v, top %v, got %v, %v, %v",
+					test.posted, test.bumped, test.top, got.posted, got.bumped, got.top)
+			}
+			if !got.posted.IsZero() && got.posted.Location().String() != "Europe/Bratislava" {
+				t.Errorf("expected date in Europe/Bratislava, got %v", got.posted.Location())
+			}
+		})
+	}
+}
+
+func TestParseDateTokenWithoutYear(t *testing.T) {
+	loc := SiteSK.TimeLocation()
+	tests := []struct {
+		text    string
+		now     time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{text: "29.2.", now: time.Date(2024, 3, 10, 12, 0, 0, 0, loc), want: time.Date(2024, 2, 29, 0, 0, 0, 0, loc)},
+		// the leap day is in the last leap year, not shifted to March 1
+		{text: "29.2.", now: time.Date(2025, 3, 10, 12, 0, 0, 0, loc), want: time.Date(2024, 2, 29, 0, 0, 0, 0, loc)},
+		{text: "29.2.", now: time.Date(2024, 1, 15, 12, 0, 0, 0, loc), want: time.Date(2020, 2, 29, 0, 0, 0, 0, loc)},
+		{text: "1.3.", now: time.Date(2025, 3, 10, 12, 0, 0, 0, loc), want: time.Date(2025, 3, 1, 0, 0, 0, 0, loc)},
+		{text: "31.2.", now: time.Date(2025, 3, 10, 12, 0, 0, 0, loc), wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseDateToken(test.text, "2.1. 2006", loc, test.now)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("%s at %v: expected error: %v, got: %v", test.text, test.now, test.wantErr, err)
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%s at %v: expected %v, got %v", test.text, test.now, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package bazos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DedupOptions controls detection of duplicate ads.
+type DedupOptions struct {
+	// TitleSimilarity is the minimal similarity (0-1) of normalized titles.
+	TitleSimilarity float64
+	// DescriptionSimilarity is the minimal similarity (0-1) of descriptions.
+	DescriptionSimilarity float64
+	// MaxImageDistance is the maximal number of differing bits of image hashes considered the same image.
+	MaxImageDistance int
+	// ImageHashes are perceptual hashes of images keyed by image URL, see Client.HashImages.
+	// Ads sharing an image are duplicates if their titles are at least half as similar as TitleSimilarity.
+	ImageHashes map[string]ImageHash
+}
+
+// DefaultDedupOptions are the options used for collapsing duplicates in listings.
+var DefaultDedupOptions = DedupOptions{
+	TitleSimilarity:       0.8,
+	DescriptionSimilarity: 0.6,
+	MaxImageDistance:      5,
+}
+
+// IsDuplicate reports whether ads a and b are likely the same item: their titles are similar
+// and either both have similar descriptions or they share an image. Ads with different
+// known sellers or postcodes are never duplicates.
+func (o DedupOptions) IsDuplicate(a, b *Ad) bool {
+	if a.ID != "" && a.ID == b.ID {
+		return true
+	}
+	if a.PostCode != "" && b.PostCode != "" && strings.ReplaceAll(a.PostCode, " ", "") != strings.ReplaceAll(b.PostCode, " ", "") {
+		return false
+	}
+	if a.Location != "" && b.Location != "" && normalizeText(a.Location) != normalizeText(b.Location) {
+		return false
+	}
+	if a.UserLink != "" && b.UserLink != "" && a.UserLink != b.UserLink {
+		return false
+	}
+	if a.UserName != "" && b.UserName != "" && !strings.EqualFold(a.UserName, b.UserName) {
+		return false
+	}
+
+	titleSim := jaccard(wordSet(a.Title), wordSet(b.Title))
+	if titleSim >= o.TitleSimilarity {
+		// a missing description does not confirm a similar title
+		descA, descB := shingles(a.Description, 3), shingles(b.Description, 3)
+		if len(descA) > 0 && len(descB) > 0 && jaccard(descA, descB) >= o.DescriptionSimilarity {
+			return true
+		}
+	}
+	return titleSim >= o.TitleSimilarity/2 && o.sameImage(a, b)
+}
+
+func (o DedupOptions) sameImage(a, b *Ad) bool {
+	if len(o.ImageHashes) == 0 {
+		return false
+	}
+	for _, ia := range adImages(a) {
+		ha, ok := o.ImageHashes[ia]
+		if !ok {
+			continue
+		}
+		for _, ib := range adImages(b) {
+			if hb, ok := o.ImageHashes[ib]; ok && ha.Distance(hb) <= o.MaxImageDistance {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarkReposts sets RepostOf of ads duplicating an older ad to the ID of the original,
+// the oldest ad they duplicate, i.e. the one created first. Ads are compared with the
+// originals only, so an ad similar to a repost but not to its original is not marked.
+// It returns the number of marked reposts.
+func MarkReposts(ads []Ad, opts DedupOptions) int {
+	order := make([]int, len(ads))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return olderAd(&ads[order[i]], &ads[order[j]])
+	})
+
+	var originals []int
+	marked := 0
+	for _, i := range order {
+		original := -1
+		for _, o := range originals {
+			if opts.IsDuplicate(&ads[o], &ads[i]) {
+				original = o
+				break
+			}
+		}
+		switch {
+		case original < 0:
+			originals = append(originals, i)
+		case ads[original].ID != ads[i].ID:
+			ads[i].RepostOf = ads[original].ID
+			marked++
+		}
+	}
+	return marked
+}
+
+// CollapseReposts returns ads without those marked as reposts by MarkReposts.
+func CollapseReposts(ads []Ad) []Ad {
+	return FilterAds(ads, func(ad Ad) bool {
+		return ad.RepostOf == ""
+	})
+}
+
+// olderAd reports whether a was created before b, comparing numeric IDs and falling back to dates.
+func olderAd(a, b *Ad) bool {
+	ia, errA := strconv.ParseInt(a.ID, 10, 64)
+	ib, errB := strconv.ParseInt(b.ID, 10, 64)
+	if errA == nil && errB == nil && ia != ib {
+		return ia < ib
+	}
+	return a.Date.Before(b.Date)
+}
+
+// ImageHash is a perceptual (average) hash of an image.
+type ImageHash uint64
+
+// Distance returns the number of differing bits of the hashes.
+func (h ImageHash) Distance(other ImageHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+func (h ImageHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// HashImage computes the average hash of img: the image is scaled down to 8x8 grayscale
+// pixels and each bit tells whether the pixel is brighter than the mean.
+func HashImage(img image.Image) ImageHash {
+	const size = 8
+	b := img.Bounds()
+	var pixels [size * size]float64
+	var sum float64
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/size, b.Min.X+(x+1)*b.Dx()/size
+			y0, y1 := b.Min.Y+y*b.Dy()/size, b.Min.Y+(y+1)*b.Dy()/size
+			var v float64
+			n := 0
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					v += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					n++
+				}
+			}
+			if n > 0 {
+				v /= float64(n)
+			}
+			pixels[y*size+x] = v
+			sum += v
+		}
+	}
+	mean := sum / float64(len(pixels))
+	var h ImageHash
+	for i, v := range pixels {
+		if v > mean {
+			h |= 1 << uint(i)
+		}
+	}
+	return h
+}
+
+// HashImages fetches images of ads and returns their hashes keyed by image URL.
+// Images that fail to download or decode are skipped.
+func (c *Client) HashImages(ctx context.Context, ads []Ad) (map[string]ImageHash, error) {
+	hashes := make(map[string]ImageHash)
+	for i := range ads {
+		for _, u := range adImages(&ads[i]) {
+			if _, ok := hashes[u]; ok {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return hashes, err
+			}
+			body, err := c.FetchImage(ctx, u)
+			if err != nil {
+				logrus.Debugf("failed to hash image %s: %v", u, err)
+				continue
+			}
+			img, _, err := image.Decode(bytes.NewReader(body))
+			if err != nil {
+				logrus.Debugf("failed to decode image %s: %v", u, err)
+				continue
+			}
+			hashes[u] = HashImage(img)
+		}
+	}
+	return hashes, nil
+}
+
+func adImages(ad *Ad) []string {
+	var images []string
+	if ad.Image != "" {
+		images = append(images, ad.Image)
+	}
+	for _, img := range ad.Images {
+		if img != "" && img != ad.Image {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
+// diacriticsReplacer folds letters with diacritics used on the sites to ASCII.
+var diacriticsReplacer = strings.NewReplacer(
+	"á", "a", "ä", "a", "ą", "a", "č", "c", "ć", "c", "ď", "d", "é", "e", "ě", "e", "ę", "e",
+	"í", "i", "ĺ", "l", "ľ", "l", "ł", "l", "ň", "n", "ń", "n", "ó", "o", "ô", "o", "ö", "o",
+	"ŕ", "r", "ř", "r", "š", "s", "ś", "s", "ß", "ss", "ť", "t", "ú", "u", "ů", "u", "ü", "u",
+	"ý", "y", "ž", "z", "ź", "z", "ż", "z",
+)
+
+// normalizeText lowercases text, folds diacritics and replaces punctuation with spaces.
+func normalizeText(s string) string {
+	s = diacriticsReplacer.Replace(strings.ToLower(s))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(normalizeText(s)) {
+		set[w] = true
+	}
+	return set
+}
+
+// shingles returns set of k consecutive words of normalized text.
+func shingles(s string, k int) map[string]bool {
+	words := strings.Fields(normalizeText(s))
+	if len(words) < k {
+		if len(words) == 0 {
+			return nil
+		}
+		return map[string]bool{strings.Join(words, " "): true}
+	}
+	set := make(map[string]bool)
+	for i := 0; i+k <= len(words); i++ {
+		set[strings.Join(words[i:i+k], " ")] = true
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of sets.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
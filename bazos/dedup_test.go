@@ -0,0 +1,141 @@
+package bazos
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDedupIsDuplicate(t *testing.T) {
+	opts := DefaultDedupOptions
+	base := Ad{
+		ID:          "150000000",
+		Title:       "Predám práčku Electrolux EW6S426W",
+		Description: "Práčka Electrolux EW6S426W, 6 kg, plne funkčná. Osobný odber v Bratislave.",
+		Location:    "Bratislava",
+		PostCode:    "841 07",
+	}
+	tests := []struct {
+		name string
+		ad   Ad
+		want bool
+	}{
+		{"same id", Ad{ID: base.ID}, true},
+		{"repost", Ad{
+			ID:          "151000000",
+			Title:       "Predam pracku ELECTROLUX EW6S426W!",
+			Description: "Práčka Electrolux EW6S426W, 6 kg, plne funkčná. Osobný odber v Bratislave. Cena dohodou.",
+			Location:    "Bratislava",
+			PostCode:    "84107",
+		}, true},
+		{"other location", Ad{
+			ID:          "151000000",
+			Title:       base.Title,
+			Description: base.Description,
+			Location:    "Košice",
+			PostCode:    "040 01",
+		}, false},
+		{"unknown seller", Ad{
+			ID:          "151000000",
+			Title:       base.Title,
+			Description: base.Description,
+			UserName:    "Jana",
+		}, true},
+		{"no description", Ad{
+			ID:       "151000000",
+			Title:    base.Title,
+			Location: "Bratislava",
+		}, false},
+		{"other item", Ad{
+			ID:          "151000000",
+			Title:       "Predám sušičku Bosch",
+			Description: "Sušička Bosch, 8 kg, ako nová.",
+			Location:    "Bratislava",
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := opts.IsDuplicate(&base, &test.ad); got != test.want {
+				t.Errorf("IsDuplicate() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	a, b := base, base
+	a.UserName, b.UserName = "Peter", "Jana"
+	b.ID = "151000000"
+	if opts.IsDuplicate(&a, &b) {
+		t.Error("ads of different sellers must not be duplicates")
+	}
+}
+
+func TestMarkReposts(t *testing.T) {
+	const (
+		desc       = "Horský bicykel Author, veľkosť L, kolesá 29, po servise. Odber Senec."
+		repostDesc = "Horský bicykel Author, veľkosť L, kolesá 29, po servise. Odber Senec alebo Pezinok."
+		// similar to the repost, but not to the original
+		editedDesc = "Author, veľkosť L, kolesá 29, po servise. Odber Senec alebo Pezinok, prípadne poštou."
+	)
+	ads := []Ad{
+		{ID: "153", Title: "Predám bicykel Author", Description: repostDesc, Location: "Senec"},
+		{ID: "140", Title: "Predám bicykel Author", Description: desc, Location: "Senec"},
+		{ID: "152", Title: "Predám kočík", Description: "Kočík, málo používaný.", Location: "Senec"},
+		{ID: "150", Title: "Predám bicykel Author", Description: desc, Location: "Senec"},
+		{ID: "155", Title: "Predám bicykel Author", Description: editedDesc, Location: "Senec"},
+		// similar title alone does not make a repost
+		{ID: "160", Title: "Predám bicykel Author", Location: "Senec"},
+	}
+	if n := MarkReposts(ads, DefaultDedupOptions); n != 2 {
+		t.Errorf("expected 2 reposts, got %d", n)
+	}
+	want := []string{"140", "", "", "140", "", ""}
+	for i, ad := range ads {
+		if ad.RepostOf != want[i] {
+			t.Errorf("ad %s: expected RepostOf %q, got %q", ad.ID, want[i], ad.RepostOf)
+		}
+	}
+	if n := len(CollapseReposts(ads)); n != 4 {
+		t.Errorf("expected 4 ads after collapsing, got %d", n)
+	}
+}
+
+func TestDedupImages(t *testing.T) {
+	img := gradientImage(64, 48, 0)
+	scaled := gradientImage(128, 96, 3)
+	other := image.NewGray(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 48; y++ {
+			other.SetGray(x, y, color.Gray{Y: uint8((x / 8 % 2) * 255)})
+		}
+	}
+	if d := HashImage(img).Distance(HashImage(scaled)); d > DefaultDedupOptions.MaxImageDistance {
+		t.Errorf("expected similar hashes, distance %d", d)
+	}
+	if d := HashImage(img).Distance(HashImage(other)); d <= DefaultDedupOptions.MaxImageDistance {
+		t.Errorf("expected different hashes, distance %d", d)
+	}
+
+	opts := DefaultDedupOptions
+	opts.ImageHashes = map[string]ImageHash{
+		"a.jpg": HashImage(img),
+		"b.jpg": HashImage(scaled),
+	}
+	a := Ad{ID: "1", Title: "Bicykel Author Grand", Description: "Predám bicykel, veľkosť M.", Images: []string{"a.jpg"}}
+	b := Ad{ID: "2", Title: "Author Grand horský bicykel 29", Description: "Zachovalý, nové brzdy a plášte.", Images: []string{"b.jpg"}}
+	if !opts.IsDuplicate(&a, &b) {
+		t.Error("expected ads sharing image to be duplicates")
+	}
+	if DefaultDedupOptions.IsDuplicate(&a, &b) {
+		t.Error("expected ads not to be duplicates without image hashes")
+	}
+}
+
+func gradientImage(w, h int, noise uint8) image.Image {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x*255/w) + uint8(y%2)*noise})
+		}
+	}
+	return img
+}
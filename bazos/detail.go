@@ -0,0 +1,197 @@
+package bazos
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+)
+
+func parseAdListing(r io.Reader, site *Site, sel *Selectors, now time.Time) (*Ad, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	ad := &Ad{}
+
+	section, err := parseSection(doc, sel)
+	if err != nil {
+		logrus.Debugf("failed to parse section: %v", err)
+		ad.addParseError("Section", "", err)
+	}
+	ad.Section = section
+
+	ad.Title = strings.TrimSpace(doc.Find(sel.Ad.Title).Text())
+	if ad.Title == "" {
+		ad.addParseError("Title", "", "title not found")
+	}
+
+	dateStr := strings.TrimSpace(doc.Find(sel.Ad.Date).Text())
+	date, err := parseAdDate(dateStr, site, sel, now)
+	if err != nil {
+		ad.addParseError("Date", dateStr, err)
+	}
+	ad.Date, ad.Bumped, ad.Top = date.posted, date.bumpedDate(), date.top
+
+	ad.Description = doc.Find(sel.Ad.Description).Text()
+
+	ad.ID = parseDetailAdID(doc, sel)
+
+	details := parseDetailTable(doc, sel)
+	labels := site.DetailLabels
+
+	if name, ok := details[labels.Name]; ok {
+		link := name.Find("a").First()
+		ad.UserName = strings.TrimSpace(link.Text())
+		ad.UserLink, _ = link.Attr("href")
+		if m := sel.sellerRegexp.FindStringSubmatch(ad.UserLink); m != nil {
+			ad.UserID = m[1]
+		}
+	}
+	if ad.UserName == "" {
+		ad.addParseError("UserName", "", "seller name not found")
+	}
+
+	if phone, ok := details[labels.Phone]; ok {
+		phone.Find("a").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			if href, ok := s.Attr("href"); ok && sel.Ad.PhoneLink != "" && strings.Contains(href, sel.Ad.PhoneLink) {
+				ad.phoneLink = href
+				return false
+			}
+			return true
+		})
+	}
+
+	if loc, ok := details[labels.Location]; ok {
+		ad.Location, ad.PostCode = parseDetailLocation(loc, sel)
+	}
+	if ad.Location == "" {
+		ad.addParseError("Location", "", "town not found")
+	}
+	if ad.PostCode == "" {
+		ad.addParseError("PostCode", "", "postcode not found")
+	}
+
+	if views, ok := details[labels.Views]; ok {
+		if ad.Views, err = parseViews(views.Text(), sel); err != nil {
+			ad.addParseError("Views", strings.TrimSpace(views.Text()), err)
+		}
+	} else {
+		ad.addParseError("Views", "", "views not found")
+	}
+
+	if price, ok := details[labels.Price]; ok {
+		priceText := strings.TrimSpace(price.Find("b").Last().Text())
+		if priceText == "" {
+			priceText = strings.TrimSpace(price.Text())
+		}
+		if ad.Price, err = parsePrice(priceText, site); err != nil {
+			ad.addParseError("Price", priceText, err)
+		}
+		if ad.Price.Kind == PriceKindInText {
+			ad.Price.Amount, _ = extractPrice(ad.Description, sel)
+		}
+	} else {
+		ad.Price = Price{Currency: site.Currency}
+		ad.addParseError("Price", "", "price not found")
+	}
+
+	doc.Find(sel.Ad.Images).Each(func(i int, s *goquery.Selection) {
+		src, _ := s.Attr(sel.Ad.ImageAttr)
+		ad.Images = append(ad.Images, src)
+	})
+
+	return ad, nil
+}
+
+// parseDetailTable returns value cells of the ad detail table keyed by row label.
+func parseDetailTable(doc *goquery.Document, sel *Selectors) map[string]*goquery.Selection {
+	details := make(map[string]*goquery.Selection)
+	doc.Find(sel.Ad.DetailRows).Each(func(i int, s *goquery.Selection) {
+		tds := s.ChildrenFiltered("td")
+		if tds.Length() < 2 {
+			return
+		}
+		label := strings.TrimSpace(tds.First().Text())
+		if !strings.HasSuffix(label, ":") {
+			return
+		}
+		label = strings.TrimSpace(strings.TrimSuffix(label, ":"))
+		if _, ok := details[label]; !ok {
+			details[label] = tds.Eq(1)
+		}
+	})
+	return details
+}
+
+// parseDetailLocation parses postcode and town from the location cell.
+func parseDetailLocation(s *goquery.Selection, sel *Selectors) (town, postCode string) {
+	var parts []string
+	links := s.Find("a")
+	if links.Length() > 0 {
+		links.Each(func(i int, s *goquery.Selection) {
+			parts = append(parts, strings.TrimSpace(s.Text()))
+		})
+	} else {
+		parts = strings.Fields(s.Text())
+	}
+	var townParts []string
+	for _, p := range parts {
+		if postCode == "" && isPostCode(p, sel) {
+			postCode = p
+		} else if p != "" {
+			townParts = append(townParts, p)
+		}
+	}
+	return strings.Join(townParts, " "), postCode
+}
+
+// parseDetailAdID extracts ad ID from links on the detail page.
+func parseDetailAdID(doc *goquery.Document, sel *Selectors) string {
+	var id string
+	doc.Find(sel.Ad.IDLinks).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		if m := sel.idRegexp.FindStringSubmatch(href); m != nil {
+			id = m[1]
+			return false
+		}
+		return true
+	})
+	return id
+}
+
+// parsePhoneNumber parses phone number from the phone reveal response.
+func parsePhoneNumber(r io.Reader, sel *Selectors) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	if href, ok := doc.Find(sel.Phone.Link).First().Attr("href"); ok {
+		return strings.TrimPrefix(href, "tel:"), nil
+	}
+	if m := sel.phoneRegexp.FindString(doc.Text()); m != "" {
+		return strings.Join(strings.Fields(m), ""), nil
+	}
+	return "", fmt.Errorf("phone number not found")
+}
+
+// resolveURL resolves ref relative to base.
+func resolveURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := b.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return r.String()
+}
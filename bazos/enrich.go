@@ -0,0 +1,158 @@
+package bazos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultEnrichWorkers is the number of detail pages fetched in parallel when
+// EnrichOptions.Workers is zero.
+const DefaultEnrichWorkers = 4
+
+// EnrichOptions controls fetching of ad details by Enrich.
+type EnrichOptions struct {
+	// Workers is the number of detail pages fetched in parallel. Requests are still paced
+	// by the crawl policy of the client. Zero means DefaultEnrichWorkers.
+	Workers int
+}
+
+func (o EnrichOptions) workers() int {
+	if o.Workers <= 0 {
+		return DefaultEnrichWorkers
+	}
+	return o.Workers
+}
+
+// EnrichError is the error of fetching detail of a single ad.
+type EnrichError struct {
+	// Index is the index of the ad in the enriched slice.
+	Index int
+	ID    string
+	Err   error
+}
+
+func (e *EnrichError) Error() string {
+	return fmt.Sprintf("ad %s: %v", e.ID, e.Err)
+}
+
+func (e *EnrichError) Unwrap() error {
+	return e.Err
+}
+
+// Enrich fetches detail pages of ads, e.g. search results, and merges detail fields
+// (full description, all images, seller) into the ads in place. Details are fetched
+// by a bounded pool of workers.
+//
+// Ads whose detail fails to fetch are left unchanged and reported as EnrichErrors ordered
+// by index, they do not stop the other ads. The returned error is non-nil only if ctx is
+// done, ads not fetched by then are left unchanged.
+func (c *Client) Enrich(ctx context.Context, ads []Ad, opts EnrichOptions) ([]*EnrichError, error) {
+	var (
+		errs = make([]*EnrichError, len(ads))
+		wg   sync.WaitGroup
+		jobs = make(chan int)
+	)
+	for w := 0; w < opts.workers() && w < len(ads); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				detail, err := c.getAdDetail(ctx, &ads[i])
+				if err != nil {
+					logrus.Debugf("failed to enrich ad %s: %v", ads[i].ID, err)
+					errs[i] = &EnrichError{Index: i, ID: ads[i].ID, Err: err}
+					continue
+				}
+				// workers receive distinct indexes, no locking is needed
+				mergeDetail(&ads[i], detail)
+			}
+		}()
+	}
+
+send:
+	for i := range ads {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed []*EnrichError
+	for _, e := range errs {
+		if e != nil {
+			failed = append(failed, e)
+		}
+	}
+	return failed, ctx.Err()
+}
+
+// getAdDetail fetches detail of the listed ad from its link, or from its detail URL.
+func (c *Client) getAdDetail(ctx context.Context, ad *Ad) (*Ad, error) {
+	u := ad.Link
+	if u == "" {
+		if ad.ID == "" {
+			return nil, fmt.Errorf("ad has no link and ID")
+		}
+		u = c.adURL(ad.ID)
+	}
+	if ad.ID == "" {
+		return c.GetAd(ctx, u)
+	}
+	return c.getAdByURL(ctx, ad.ID, u)
+}
+
+// mergeDetail merges fields of the ad detail into the listed ad. Fields available only
+// on the detail page are taken from detail, others fill in fields missing in the listing.
+func mergeDetail(ad, detail *Ad) {
+	if detail.Description != "" {
+		// listings show shortened descriptions
+		ad.Description = detail.Description
+	}
+	if len(detail.Images) > 0 {
+		ad.Images = detail.Images
+	}
+	ad.UserName = detail.UserName
+	ad.UserLink = detail.UserLink
+	ad.UserID = detail.UserID
+	ad.Email = detail.Email
+	ad.PhoneNumber = detail.PhoneNumber
+	if detail.Views > ad.Views {
+		ad.Views = detail.Views
+	}
+
+	if ad.ID == "" {
+		ad.ID = detail.ID
+	}
+	if ad.Link == "" {
+		ad.Link = detail.Link
+	}
+	if ad.Title == "" {
+		ad.Title = detail.Title
+	}
+	if ad.Date.IsZero() {
+		ad.Date = detail.Date
+	}
+	if ad.Bumped == nil {
+		ad.Bumped = detail.Bumped
+	}
+	if ad.Section == nil {
+		ad.Section = detail.Section
+	}
+	if ad.Price.Kind == PriceKindUnknown {
+		ad.Price = detail.Price
+	}
+	if ad.Location == "" {
+		ad.Location, ad.PostCode = detail.Location, detail.PostCode
+	}
+
+	for _, e := range detail.ParseErrors {
+		e.Field = "Detail." + e.Field
+		ad.ParseErrors = append(ad.ParseErrors, e)
+	}
+}
@@ -0,0 +1,65 @@
+package bazos
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClientEnrich(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/inzerat/150722248/nosic.php", "ad_detail.html")
+	srv.Handle("/inzerat/150722248/", "ad_detail.html")
+	srv.Handle("/inzerat/151111111/darujem-staru-pracku.php", "ad_detail.html")
+
+	base := "http://auto." + testDomain
+	ads := []Ad{
+		{ID: "150722248", Title: "Nosič bicyklov", Link: base + "/inzerat/150722248/nosic.php", Description: "Predám nosič", Top: true, Views: 10},
+		// the detail page shows another ad
+		{ID: "151111111", Title: "Darujem starú práčku", Link: base + "/inzerat/151111111/darujem-staru-pracku.php", Description: "Práčka"},
+		// ads without link are fetched from their detail URL
+		{ID: "150722248"},
+	}
+	errs, err := client.Enrich(context.Background(), ads, EnrichOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != 1 || errs[0].Index != 1 || !errors.Is(errs[0], ErrAdNotFound) {
+		t.Fatalf("expected not found error of ad #1, got %v", errs)
+	}
+	if ads[1].Description != "Práčka" || ads[1].UserName != "" {
+		t.Errorf("expected failed ad unchanged, got %+v", ads[1])
+	}
+
+	ad := ads[0]
+	if ad.Title != "Nosič bicyklov" || !ad.Top || ad.Link != base+"/inzerat/150722248/nosic.php" {
+		t.Errorf("expected listing fields kept, got %+v", ad)
+	}
+	if ad.UserName != "Peter" || ad.UserID != "1234567" || len(ad.Images) != 2 || ad.Views != 523 {
+		t.Errorf("expected detail fields merged, got %+v", ad)
+	}
+	if ad.Description == "Predám nosič" {
+		t.Error("expected full description from detail")
+	}
+	if ads[2].Title != "Predám nosič bicyklov na ťažné" || ads[2].Price.Amount != 45 || ads[2].Location != "Bratislava" {
+		t.Errorf("expected missing listing fields filled from detail, got %+v", ads[2])
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestClientEnrichCanceled(t *testing.T) {
+	_, client := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ads := []Ad{{ID: "150722248", Description: "Predám nosič"}}
+	if _, err := client.Enrich(ctx, ads, EnrichOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got %v", err)
+	}
+	if ads[0].Description != "Predám nosič" {
+		t.Errorf("expected ad unchanged, got %+v", ads[0])
+	}
+}
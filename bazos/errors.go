@@ -0,0 +1,137 @@
+package bazos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Errors returned by the client, possibly wrapped with details. Use errors.Is to test for them.
+var (
+	// ErrAdNotFound is returned when no ad with the requested ID exists.
+	ErrAdNotFound = errors.New("ad not found")
+	// ErrAdRemoved is returned for ads that existed but were removed from the site.
+	ErrAdRemoved = errors.New("ad removed")
+	// ErrNoResults is reported for search result pages stating no ads match the search.
+	ErrNoResults = errors.New("no ads found")
+	// ErrBlocked is returned when the site refuses requests of the client, either by
+	// status code (403, or 429 after retries) or by serving a captcha challenge.
+	ErrBlocked = errors.New("blocked by site")
+	// ErrLayoutChanged is returned for pages missing elements required by the parser,
+	// see LayoutError.
+	ErrLayoutChanged = errors.New("page layout changed")
+	// ErrDisallowedByRobots is returned for requests disallowed by robots.txt.
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+)
+
+// StatusError is returned for responses with non-200 status code.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is the delay requested by the server via Retry-After header.
+	RetryAfter time.Duration
+
+	hasRetryAfter bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected response status %s for %s", e.Status, e.URL)
+}
+
+// Is reports refused requests as ErrBlocked.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrBlocked && (e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusTooManyRequests)
+}
+
+// Temporary reports whether the request may succeed when retried.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// LayoutError is returned for pages that cannot be parsed, usually because the site
+// changed its layout. It matches ErrLayoutChanged.
+type LayoutError struct {
+	URL         string
+	Diagnostics Diagnostics
+}
+
+func (e *LayoutError) Error() string {
+	return fmt.Sprintf("%v: %s not found on %s", ErrLayoutChanged, strings.Join(e.Diagnostics.Missing, ", "), e.URL)
+}
+
+func (e *LayoutError) Is(target error) bool {
+	return target == ErrLayoutChanged
+}
+
+// Diagnostics describes problems found while parsing a page. Missing elements indicate
+// a changed layout of the site, while parse errors are usually caused by unexpected
+// content of individual ads.
+type Diagnostics struct {
+	// Missing are the page elements required by the parser that were not found.
+	Missing []string `json:",omitempty" yaml:",omitempty"`
+	// ParseErrors are errors of parsed fields, for listing pages including errors of the listed ads.
+	ParseErrors []ParseError `json:",omitempty" yaml:",omitempty"`
+}
+
+// OK reports whether the page was parsed without problems.
+func (d Diagnostics) OK() bool {
+	return len(d.Missing) == 0 && len(d.ParseErrors) == 0
+}
+
+// LayoutChanged reports whether required elements are missing on the page.
+func (d Diagnostics) LayoutChanged() bool {
+	return len(d.Missing) > 0
+}
+
+func (d *Diagnostics) addMissing(element string) {
+	d.Missing = append(d.Missing, element)
+}
+
+// requiredAdFields are fields of ad detail present on every ad page, the parse error
+// "not found" of any of them means the layout changed.
+var requiredAdFields = []string{"Title", "UserName", "Views"}
+
+// Diagnostics returns diagnostics of parsing the ad detail.
+func (ad Ad) Diagnostics() Diagnostics {
+	d := Diagnostics{ParseErrors: ad.ParseErrors}
+	for _, field := range requiredAdFields {
+		for _, e := range ad.ParseErrors {
+			if e.Field == field && e.Value == "" {
+				d.addMissing(field)
+				break
+			}
+		}
+	}
+	return d
+}
+
+// captchaMarkers are parts of challenge pages served instead of content to blocked clients.
+var captchaMarkers = [][]byte{
+	[]byte("g-recaptcha"),
+	[]byte("h-captcha"),
+	[]byte("cf-challenge"),
+	[]byte("challenge-platform"),
+}
+
+// checkBlocked returns ErrBlocked if body of page at u is a captcha challenge. Pages with
+// content matching the expected selector are not challenges even with a captcha marker,
+// e.g. ad details embed a recaptcha widget in the contact form.
+func checkBlocked(u string, body []byte, expected string) error {
+	for _, marker := range captchaMarkers {
+		if !bytes.Contains(body, marker) {
+			continue
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err == nil && doc.Find(expected).Length() > 0 {
+			return nil
+		}
+		return fmt.Errorf("%w: captcha challenge at %s", ErrBlocked, u)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package bazos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatusErrorIs(t *testing.T) {
+	tests := []struct {
+		status  int
+		blocked bool
+	}{
+		{http.StatusForbidden, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusNotFound, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, test := range tests {
+		err := fmt.Errorf("failed to fetch URL: %w", &StatusError{StatusCode: test.status})
+		if got := errors.Is(err, ErrBlocked); got != test.blocked {
+			t.Errorf("status %d: expected blocked %v, got %v", test.status, test.blocked, got)
+		}
+	}
+}
+
+func TestParseAdListingsPageDiagnostics(t *testing.T) {
+	page, err := parseAdListingsPage(openFixture(t, "search_empty.html"), SiteSK, defaultSelectors(SiteSK), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.NoResults || !page.Diagnostics.OK() {
+		t.Errorf("expected page without results and problems, got %+v", page)
+	}
+
+	page, err = parseAdListingsPage(strings.NewReader(`<html><body><div class="results"><a href="/x">x</a></div></body></html>`), SiteSK, defaultSelectors(SiteSK), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"listings info", "ad listings"}; !reflect.DeepEqual(page.Diagnostics.Missing, want) {
+		t.Errorf("expected missing %v, got %v", want, page.Diagnostics.Missing)
+	}
+}
+
+func TestAdDiagnostics(t *testing.T) {
+	ad, err := parseAdListing(openFixture(t, "ad_detail.html"), SiteSK, defaultSelectors(SiteSK), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.Diagnostics().LayoutChanged() {
+		t.Errorf("unexpected diagnostics: %+v", ad.Diagnostics())
+	}
+
+	ad, err = parseAdListing(openFixture(t, "search_empty.html"), SiteSK, defaultSelectors(SiteSK), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := requiredAdFields; !reflect.DeepEqual(ad.Diagnostics().Missing, want) {
+		t.Errorf("expected missing %v, got %v", want, ad.Diagnostics().Missing)
+	}
+}
+
+func TestClientPageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"captcha", `<html><body><form><div class="g-recaptcha" data-sitekey="x"></div></form></body></html>`, ErrBlocked},
+		{"layout", `<html><body><div class="results"></div></body></html>`, ErrLayoutChanged},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, client := newStubClient(t, CrawlPolicy{}, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, test.body)
+			})
+			_, err := client.ListAds(context.Background(), srv.URL+"/search.php?hledat=x")
+			if !errors.Is(err, test.want) {
+				t.Errorf("expected %v, got %v", test.want, err)
+			}
+		})
+	}
+
+	srv, client := newStubClient(t, CrawlPolicy{}, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	_, err := client.ListAds(context.Background(), srv.URL+"/search.php?hledat=x")
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) || layoutErr.URL != srv.URL+"/search.php?hledat=x" {
+		t.Errorf("expected layout error with URL, got %v", err)
+	}
+}
+
+func TestCheckBlocked(t *testing.T) {
+	sel := defaultSelectors(SiteSK)
+	fixture := func(name string) string {
+		body, err := io.ReadAll(openFixture(t, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	challenge := `<html><body><form><div class="g-recaptcha" data-sitekey="x"></div></form></body></html>`
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+		blocked  bool
+	}{
+		{"ad", fixture("ad_detail.html"), sel.Ad.Title, false},
+		// the contact form of ad details embeds a recaptcha widget
+		{"ad with captcha widget", fixture("ad_detail_recaptcha.html"), sel.Ad.Title, false},
+		{"listings", fixture("search_electrolux.html"), sel.Listings.Info, false},
+		{"challenge", challenge, sel.Ad.Title, true},
+		{"challenge instead of listings", challenge, sel.Listings.Info, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkBlocked("https://www.bazos.sk/", []byte(test.body), test.expected)
+			if blocked := errors.Is(err, ErrBlocked); blocked != test.blocked {
+				t.Errorf("expected blocked %v, got %v", test.blocked, err)
+			}
+		})
+	}
+}
+
+func TestClientGetAdWithCaptchaWidget(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/inzerat/150722248/", "ad_detail_recaptcha.html")
+
+	ad, err := client.GetAdById(context.Background(), "150722248")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.ID != "150722248" || ad.Title == "" {
+		t.Errorf("unexpected ad: %+v", ad)
+	}
+}
@@ -0,0 +1,265 @@
+package bazos
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// Feed is a feed of ads, e.g. results of a saved search, rendered as RSS 2.0 or Atom.
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	// Updated is the time of the feed, used for ads without date.
+	Updated time.Time
+	Ads     []Ad
+}
+
+// NewFeed returns feed of ads found by the query.
+func (c *Client) NewFeed(ctx context.Context, q SearchQuery, opts ListOptions) (*Feed, error) {
+	site, domain, err := c.searchSite(q)
+	if err != nil {
+		return nil, err
+	}
+	u, err := q.toUrl(c.scheme, domain)
+	if err != nil {
+		return nil, err
+	}
+	ads, err := c.IterAds(ctx, q, opts).All()
+	if err != nil {
+		return nil, err
+	}
+	return &Feed{
+		Title:       q.feedTitle(site),
+		Link:        u.String(),
+		Description: fmt.Sprintf("Ads matching the search on %s", site.Domain),
+		Updated:     time.Now(),
+		Ads:         ads,
+	}, nil
+}
+
+func (q SearchQuery) feedTitle(site *Site) string {
+	var parts []string
+	if q.Query != "" {
+		parts = append(parts, q.Query)
+	}
+	if q.Section != nil && q.Section.Section != "" {
+		section := site.SectionName(q.Section.Section)
+		if q.Section.Category != "" {
+			section += "/" + q.Section.Category
+		}
+		parts = append(parts, section)
+	}
+	if q.Location != "" {
+		parts = append(parts, fmt.Sprintf("%s +%d km", q.Location, q.Vicinity))
+	}
+	if len(parts) == 0 {
+		return site.Domain
+	}
+	return strings.Join(parts, " - ") + " - " + site.Domain
+}
+
+// itemTitle returns title of the ad with its price.
+func itemTitle(ad Ad) string {
+	if ad.Price.Kind == PriceKindUnknown {
+		return ad.Title
+	}
+	return fmt.Sprintf("%s - %s", ad.Title, ad.Price)
+}
+
+// itemContent returns HTML content of the ad with its thumbnail, price, location and description.
+func itemContent(ad Ad) string {
+	var b strings.Builder
+	if images := adImages(&ad); len(images) > 0 {
+		fmt.Fprintf(&b, `<p><img src="%s" alt="%s"></p>`, html.EscapeString(images[0]), html.EscapeString(ad.Title))
+	}
+	fmt.Fprintf(&b, "<p><b>%s</b>", html.EscapeString(ad.Price.String()))
+	if location := strings.TrimSpace(ad.Location + " " + ad.PostCode); location != "" {
+		fmt.Fprintf(&b, " - %s", html.EscapeString(location))
+	}
+	b.WriteString("</p>")
+	if ad.Description != "" {
+		fmt.Fprintf(&b, "<p>%s</p>", strings.ReplaceAll(html.EscapeString(ad.Description), "\n", "<br>"))
+	}
+	return b.String()
+}
+
+// itemTime returns time of the feed item, bumped ads are dated as listed again.
+func (f *Feed) itemTime(ad Ad) time.Time {
+	if listed := ad.Listed(); !listed.IsZero() {
+		return listed
+	}
+	return f.Updated
+}
+
+func itemID(ad Ad) string {
+	if ad.Link != "" {
+		return ad.Link
+	}
+	return "urn:bazos:ad:" + ad.ID
+}
+
+// mediaNamespace is the namespace of Media RSS elements.
+const mediaNamespace = "http://search.yahoo.com/mrss/"
+
+type rss struct {
+	XMLName    xml.Name   `xml:"rss"`
+	Version    string     `xml:"version,attr"`
+	MediaXMLNS string     `xml:"xmlns:media,attr"`
+	Channel    rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string          `xml:"title"`
+	Link        string          `xml:"link,omitempty"`
+	Description string          `xml:"description"`
+	GUID        rssGUID         `xml:"guid"`
+	PubDate     string          `xml:"pubDate,omitempty"`
+	Category    string          `xml:"category,omitempty"`
+	Thumbnail   *mediaThumbnail `xml:"media:thumbnail"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// mediaThumbnail is the Media RSS thumbnail of an item. Unlike an enclosure it needs
+// no length and type of the image, which are not known without downloading it.
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// WriteRSS writes the feed as RSS 2.0.
+func (f *Feed) WriteRSS(w io.Writer) error {
+	doc := rss{
+		Version:    "2.0",
+		MediaXMLNS: mediaNamespace,
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: f.Description,
+		},
+	}
+	if !f.Updated.IsZero() {
+		doc.Channel.LastBuildDate = f.Updated.Format(time.RFC1123Z)
+	}
+	for _, ad := range f.Ads {
+		item := rssItem{
+			Title:       itemTitle(ad),
+			Link:        ad.Link,
+			Description: itemContent(ad),
+			GUID:        rssGUID{IsPermaLink: ad.Link != "", Value: itemID(ad)},
+		}
+		if t := f.itemTime(ad); !t.IsZero() {
+			item.PubDate = t.Format(time.RFC1123Z)
+		}
+		if ad.Section != nil {
+			item.Category = ad.Section.Section
+		}
+		if images := adImages(&ad); len(images) > 0 {
+			item.Thumbnail = &mediaThumbnail{URL: images[0]}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomName    `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published,omitempty"`
+	Links     []atomLink `xml:"link"`
+	Author    *atomName  `xml:"author"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomName struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteAtom writes the feed as Atom.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	updated := f.Updated
+	for _, ad := range f.Ads {
+		if listed := ad.Listed(); listed.After(updated) {
+			updated = listed
+		}
+	}
+	doc := atomFeed{
+		Title:   f.Title,
+		ID:      f.Link,
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomName{Name: "bazos"},
+		Links:   []atomLink{{Href: f.Link, Rel: "alternate", Type: "text/html"}},
+	}
+	for _, ad := range f.Ads {
+		t := f.itemTime(ad).Format(time.RFC3339)
+		entry := atomEntry{
+			Title:     itemTitle(ad),
+			ID:        itemID(ad),
+			Updated:   t,
+			Published: t,
+			Content:   atomText{Type: "html", Value: itemContent(ad)},
+		}
+		if ad.Link != "" {
+			entry.Links = append(entry.Links, atomLink{Href: ad.Link, Rel: "alternate", Type: "text/html"})
+		}
+		if images := adImages(&ad); len(images) > 0 {
+			entry.Links = append(entry.Links, atomLink{Href: images[0], Rel: "enclosure"})
+		}
+		if ad.UserName != "" {
+			entry.Author = &atomName{Name: ad.UserName}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,93 @@
+package bazos
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFeed(t *testing.T) *Feed {
+	t.Helper()
+	srv, client := newTestServer(t)
+	srv.Handle("elektro."+testDomain+"/pracky/", "search_electrolux_3.html")
+
+	feed, err := client.NewFeed(context.Background(), SearchQuery{
+		Query:    "electrolux",
+		Section:  &AdSection{Section: "elektro", Category: "pracky"},
+		Vicinity: DefaultVicinity,
+	}, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Updated = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	return feed
+}
+
+func TestFeedRSS(t *testing.T) {
+	feed := newTestFeed(t)
+	if feed.Title != "electrolux - Elektro/pracky - bazos.sk" {
+		t.Errorf("unexpected feed title %q", feed.Title)
+	}
+
+	var buf bytes.Buffer
+	if err := feed.WriteRSS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc rss
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid RSS: %v\n%s", err, buf.String())
+	}
+	if doc.Version != "2.0" || !strings.HasPrefix(doc.Channel.Link, "http://elektro.bazos.test/pracky/?") {
+		t.Errorf("unexpected channel %+v", doc.Channel)
+	}
+	if len(doc.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != "Electrolux sušička - 1250 EUR" {
+		t.Errorf("unexpected item title %q", item.Title)
+	}
+	if item.GUID.Value != item.Link || !strings.HasPrefix(item.Link, "http://elektro.bazos.test/inzerat/150888888/") {
+		t.Errorf("unexpected item link %q, guid %q", item.Link, item.GUID.Value)
+	}
+	thumbnail := "https://www.bazos.sk/img/1t/888/150888888.jpg"
+	if !strings.Contains(buf.String(), `<media:thumbnail url="`+thumbnail+`">`) ||
+		!strings.Contains(item.Description, `<img src="`+thumbnail+`"`) {
+		t.Errorf("expected thumbnail in media:thumbnail and description, got %+v", item)
+	}
+	if strings.Contains(buf.String(), "<enclosure") {
+		t.Error("expected no enclosure without image length and type")
+	}
+	if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+		t.Errorf("invalid pubDate: %v", err)
+	}
+}
+
+func TestFeedAtom(t *testing.T) {
+	feed := newTestFeed(t)
+
+	var buf bytes.Buffer
+	if err := feed.WriteAtom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid Atom: %v\n%s", err, buf.String())
+	}
+	if doc.XMLName.Space != "http://www.w3.org/2005/Atom" || doc.ID != feed.Link {
+		t.Errorf("unexpected feed %+v", doc)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(doc.Entries))
+	}
+	entry := doc.Entries[1]
+	if entry.Title != "Práčka Electrolux na diely - 30 EUR" || entry.Content.Type != "html" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if !strings.Contains(entry.Content.Value, "30 EUR") {
+		t.Errorf("expected price in content, got %q", entry.Content.Value)
+	}
+}
@@ -0,0 +1,344 @@
+package bazos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+)
+
+// PageType is the type of page checked by the health check.
+type PageType string
+
+const (
+	PageTypeListings PageType = "listings"
+	PageTypeAd       PageType = "ad"
+)
+
+// DefaultHealthQuery is the search used by CheckHealth to get reference pages,
+// it matches ads on every site.
+var DefaultHealthQuery = SearchQuery{Query: "iphone", Vicinity: DefaultVicinity}
+
+// SelectorCheck reports matches of a CSS selector used by the parser.
+type SelectorCheck struct {
+	Selector string
+	// Required selectors match on every page of the type, others e.g. only on ads with photos.
+	Required bool
+	Matches  int
+}
+
+// OK reports whether the selector matches if required.
+func (s SelectorCheck) OK() bool {
+	return !s.Required || s.Matches > 0
+}
+
+// FieldCheck reports how many ads on the page have the field parsed.
+type FieldCheck struct {
+	Field string
+	// Required fields are present in every ad, others may be empty.
+	Required bool
+	Parsed   int
+	Total    int
+}
+
+// OK reports whether the field is parsed in all ads if required.
+func (f FieldCheck) OK() bool {
+	return !f.Required || f.Parsed == f.Total
+}
+
+// PageCheck is the result of checking a single page.
+type PageCheck struct {
+	Type PageType
+	// Source is the URL or file the page was read from.
+	Source      string
+	Selectors   []SelectorCheck `json:",omitempty" yaml:",omitempty"`
+	Fields      []FieldCheck    `json:",omitempty" yaml:",omitempty"`
+	Diagnostics Diagnostics
+	// Error is the error of fetching or parsing the page.
+	Error string `json:",omitempty" yaml:",omitempty"`
+}
+
+// OK reports whether the page was parsed and all checks passed.
+func (p PageCheck) OK() bool {
+	if p.Error != "" || p.Diagnostics.LayoutChanged() {
+		return false
+	}
+	for _, s := range p.Selectors {
+		if !s.OK() {
+			return false
+		}
+	}
+	for _, f := range p.Fields {
+		if !f.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// HealthReport is the result of checking parsers against reference pages.
+type HealthReport struct {
+	Site    string
+	Checked time.Time
+	Pages   []PageCheck
+}
+
+// OK reports whether all pages passed the checks.
+func (r *HealthReport) OK() bool {
+	for _, p := range r.Pages {
+		if !p.OK() {
+			return false
+		}
+	}
+	return len(r.Pages) > 0
+}
+
+type selectorSpec struct {
+	selector string
+	required bool
+}
+
+type fieldSpec struct {
+	field    string
+	required bool
+}
+
+// listingsSelectors returns the selectors used by parseAdListingsPage.
+func listingsSelectors(sel *Selectors) []selectorSpec {
+	ad := sel.Listings.Ad + " "
+	return []selectorSpec{
+		{sel.Listings.Info, true},
+		{sel.Listings.Ad, true},
+		{ad + sel.Listings.Title, true},
+		{ad + sel.Listings.AdLink, true},
+		{ad + sel.Listings.Date, true},
+		{ad + sel.Listings.Description, true},
+		{ad + sel.Listings.Price, true},
+		{ad + sel.Listings.Location, true},
+		{ad + sel.Listings.Views, true},
+		{ad + sel.Listings.Image, false},
+		{sel.Listings.PageLinks, false},
+		{sel.Breadcrumbs, false},
+	}
+}
+
+var listingsFields = []fieldSpec{
+	{"ID", true},
+	{"Title", true},
+	{"Link", true},
+	{"Date", true},
+	{"Price", true},
+	{"Location", true},
+	{"PostCode", false},
+	{"Description", false},
+	{"Views", false},
+	{"Images", false},
+}
+
+// adSelectors returns the selectors used by parseAdListing.
+func adSelectors(sel *Selectors) []selectorSpec {
+	return []selectorSpec{
+		{sel.Ad.Title, true},
+		{sel.Ad.Date, true},
+		{sel.Ad.Description, true},
+		{sel.Breadcrumbs, true},
+		{sel.Ad.DetailRows, true},
+		{sel.Ad.Images, false},
+	}
+}
+
+var adFields = []fieldSpec{
+	{"ID", true},
+	{"Title", true},
+	{"Date", true},
+	{"Price", true},
+	{"Description", true},
+	{"UserName", true},
+	{"Location", true},
+	{"Views", true},
+	{"Section", true},
+	{"PostCode", false},
+	{"Images", false},
+}
+
+// CheckListingsPage checks page of ad listings read from r, e.g. a saved fixture, using
+// selectors sel (default selectors of site if nil). The page is expected to list ads.
+func CheckListingsPage(r io.Reader, site *Site, sel *Selectors) PageCheck {
+	if sel == nil {
+		sel = DefaultSelectorProfile().ForSite(site)
+	}
+	check := PageCheck{Type: PageTypeListings}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if check.Selectors, err = checkSelectors(body, listingsSelectors(sel)); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	page, err := parseAdListingsPage(bytes.NewReader(body), site, sel, time.Now())
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Diagnostics = page.Diagnostics
+	if page.NoResults {
+		check.Error = "reference page lists no ads"
+	}
+	check.Fields = checkFields(page.AdListings, listingsFields)
+	return check
+}
+
+// CheckAdPage checks ad detail page read from r, e.g. a saved fixture, using selectors sel
+// (default selectors of site if nil).
+func CheckAdPage(r io.Reader, site *Site, sel *Selectors) PageCheck {
+	if sel == nil {
+		sel = DefaultSelectorProfile().ForSite(site)
+	}
+	check := PageCheck{Type: PageTypeAd}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if check.Selectors, err = checkSelectors(body, adSelectors(sel)); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	ad, err := parseAdListing(bytes.NewReader(body), site, sel, time.Now())
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Diagnostics = ad.Diagnostics()
+	check.Fields = checkFields([]Ad{*ad}, adFields)
+	return check
+}
+
+// CheckHealth fetches reference pages, the first result page of q and detail of its first ad,
+// and checks that the parsers still understand them. Pages are fetched bypassing the cache.
+func (c *Client) CheckHealth(ctx context.Context, q SearchQuery) (*HealthReport, error) {
+	site, domain, err := c.searchSite(q)
+	if err != nil {
+		return nil, err
+	}
+	u, err := q.toUrl(c.scheme, domain)
+	if err != nil {
+		return nil, err
+	}
+	report := &HealthReport{Site: site.Code, Checked: c.now()}
+	sel := c.selectorsFor(site)
+
+	logrus.Debugf("checking listings page: %v", u)
+	body, pageUrl, err := c.fetch(ctx, u.String(), pageHealth)
+	if err == nil {
+		err = checkBlocked(pageUrl, body, sel.Listings.Info)
+	}
+	if err != nil {
+		report.Pages = append(report.Pages, PageCheck{Type: PageTypeListings, Source: u.String(), Error: err.Error()})
+		return report, nil
+	}
+	listings := CheckListingsPage(bytes.NewReader(body), site, sel)
+	listings.Source = pageUrl
+	report.Pages = append(report.Pages, listings)
+
+	adUrl := ""
+	if page, err := parseAdListingsPage(bytes.NewReader(body), site, sel, c.now()); err == nil {
+		for _, ad := range page.AdListings {
+			if ad.Link != "" {
+				adUrl = resolveURL(pageUrl, ad.Link)
+				break
+			}
+		}
+	}
+	if adUrl == "" {
+		report.Pages = append(report.Pages, PageCheck{Type: PageTypeAd, Error: "no ad link found on listings page"})
+		return report, nil
+	}
+
+	logrus.Debugf("checking ad page: %v", adUrl)
+	body, pageUrl, err = c.fetch(ctx, adUrl, pageHealth)
+	if err == nil {
+		err = checkBlocked(pageUrl, body, sel.Ad.Title)
+	}
+	if err != nil {
+		report.Pages = append(report.Pages, PageCheck{Type: PageTypeAd, Source: adUrl, Error: err.Error()})
+		return report, nil
+	}
+	ad := CheckAdPage(bytes.NewReader(body), site, sel)
+	ad.Source = pageUrl
+	report.Pages = append(report.Pages, ad)
+	return report, nil
+}
+
+func checkSelectors(body []byte, specs []selectorSpec) ([]SelectorCheck, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	checks := make([]SelectorCheck, 0, len(specs))
+	for _, spec := range specs {
+		checks = append(checks, SelectorCheck{
+			Selector: spec.selector,
+			Required: spec.required,
+			Matches:  doc.Find(spec.selector).Length(),
+		})
+	}
+	return checks, nil
+}
+
+func checkFields(ads []Ad, specs []fieldSpec) []FieldCheck {
+	checks := make([]FieldCheck, 0, len(specs))
+	for _, spec := range specs {
+		check := FieldCheck{Field: spec.field, Required: spec.required, Total: len(ads)}
+		for _, ad := range ads {
+			if fieldParsed(ad, spec.field) {
+				check.Parsed++
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// fieldParsed reports whether field of ad has value and no parse error.
+func fieldParsed(ad Ad, field string) bool {
+	for _, e := range ad.ParseErrors {
+		if e.Field == field {
+			return false
+		}
+	}
+	switch field {
+	case "ID":
+		return ad.ID != ""
+	case "Title":
+		return ad.Title != ""
+	case "Link":
+		return ad.Link != ""
+	case "Date":
+		return !ad.Date.IsZero()
+	case "Price":
+		return ad.Price.Kind != PriceKindUnknown
+	case "Description":
+		return ad.Description != ""
+	case "UserName":
+		return ad.UserName != ""
+	case "Location":
+		return ad.Location != ""
+	case "PostCode":
+		return ad.PostCode != ""
+	case "Views":
+		return ad.Views > 0
+	case "Images":
+		return ad.HasPhoto()
+	case "Section":
+		return ad.Section != nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,82 @@
+package bazos
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckPages(t *testing.T) {
+	tests := []struct {
+		fixture string
+		check   func(t *testing.T, fixture string) PageCheck
+		ok      bool
+	}{
+		{"search_electrolux.html", checkListingsFixture, true},
+		{"search_electrolux_3.html", checkListingsFixture, true},
+		{"ad_detail.html", checkAdFixture, true},
+		// no results is not a reference page
+		{"search_empty.html", checkListingsFixture, false},
+		// wrong page type simulates changed layout
+		{"ad_detail.html", checkListingsFixture, false},
+		{"search_electrolux.html", checkAdFixture, false},
+	}
+	for _, test := range tests {
+		check := test.check(t, test.fixture)
+		if check.OK() != test.ok {
+			t.Errorf("%s as %s: expected OK %v, got %+v", test.fixture, check.Type, test.ok, check)
+		}
+	}
+}
+
+func checkListingsFixture(t *testing.T, fixture string) PageCheck {
+	return CheckListingsPage(openFixture(t, fixture), SiteSK, nil)
+}
+
+func checkAdFixture(t *testing.T, fixture string) PageCheck {
+	return CheckAdPage(openFixture(t, fixture), SiteSK, nil)
+}
+
+func TestCheckListingsPageFields(t *testing.T) {
+	check := CheckListingsPage(openFixture(t, "search_electrolux.html"), SiteSK, nil)
+	for _, f := range check.Fields {
+		if f.Total != 3 {
+			t.Errorf("field %s: expected 3 ads, got %d", f.Field, f.Total)
+		}
+	}
+	for _, s := range check.Selectors {
+		if s.Selector == ".inzeraty.inzeratyflex" && s.Matches != 3 {
+			t.Errorf("expected 3 matches of %s, got %d", s.Selector, s.Matches)
+		}
+	}
+}
+
+func TestClientCheckHealth(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_electrolux.html")
+	srv.Handle("/inzerat/151234567/predam-pracku-electrolux.php", "ad_detail.html")
+
+	report, err := client.CheckHealth(context.Background(), DefaultHealthQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pages) != 2 {
+		t.Fatalf("expected 2 checked pages, got %+v", report.Pages)
+	}
+	if !report.OK() {
+		t.Errorf("expected healthy report, got %+v", report)
+	}
+	if want := "http://" + testDomain + "/inzerat/151234567/predam-pracku-electrolux.php"; report.Pages[1].Source != want {
+		t.Errorf("expected ad page %s, got %s", want, report.Pages[1].Source)
+	}
+
+	// missing ad page breaks the check
+	srv, client = newTestServer(t)
+	srv.Handle("/search.php", "search_electrolux.html")
+	report, err = client.CheckHealth(context.Background(), DefaultHealthQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() || report.Pages[1].Error == "" {
+		t.Errorf("expected failed ad page, got %+v", report.Pages)
+	}
+}
@@ -0,0 +1,158 @@
+// Package history records observations of ads over time and computes market statistics.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/internal/fsutil"
+)
+
+// Observation is a state of an ad observed at given time.
+type Observation struct {
+	Time   time.Time
+	AdID   string
+	Title  string
+	Posted time.Time `json:",omitempty"`
+	Price  bazos.Price
+	Views  int  `json:",omitempty"`
+	Top    bool `json:",omitempty"`
+}
+
+// NewObservation returns observation of ad at time t.
+func NewObservation(ad bazos.Ad, t time.Time) Observation {
+	return Observation{
+		Time:   t,
+		AdID:   ad.ID,
+		Title:  ad.Title,
+		Posted: ad.Date,
+		Price:  ad.Price,
+		Views:  ad.Views,
+		Top:    ad.Top,
+	}
+}
+
+// Store keeps observations in a directory, one JSON Lines file per series.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a Store in dir, creating the directory if needed.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// DefaultStoreDir returns the default directory of the store.
+func DefaultStoreDir() string {
+	return fsutil.CacheDir("history")
+}
+
+func (s *Store) path(series string) string {
+	return filepath.Join(s.dir, fsutil.SafeName(series)+".jsonl")
+}
+
+// Record appends observations of ads at time t to the series.
+func (s *Store) Record(series string, ads []bazos.Ad, t time.Time) error {
+	f, err := os.OpenFile(s.path(series), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, ad := range ads {
+		if ad.ID == "" {
+			continue
+		}
+		if err := enc.Encode(NewObservation(ad, t)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load returns all observations of the series in recorded order.
+func (s *Store) Load(series string) ([]Observation, error) {
+	f, err := os.Open(s.path(series))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []Observation
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var o Observation
+		if err := dec.Decode(&o); err != nil {
+			return nil, fmt.Errorf("failed to decode history of %q: %w", series, err)
+		}
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+// SeriesName returns the series name for observations of ads matching the query. All query
+// fields are part of the name, queries differing only in sort order or filters have
+// separate series.
+func SeriesName(q bazos.SearchQuery) string {
+	parts := []string{q.Site}
+	if parts[0] == "" {
+		parts[0] = bazos.DefaultSite.Code
+	}
+	if q.Section != nil {
+		parts = append(parts, q.Section.Section, q.Section.Category)
+	}
+	parts = append(parts, q.Query, q.Location)
+	if q.Location != "" {
+		parts = append(parts, fmt.Sprint(q.Vicinity))
+	}
+	if q.PriceFrom > 0 || q.PriceTo > 0 {
+		parts = append(parts, fmt.Sprintf("%d-%d", q.PriceFrom, q.PriceTo))
+	}
+	// sort order and client-side filters change the observed ads as well
+	parts = append(parts, string(q.Sort))
+	if q.ExcludeTop {
+		parts = append(parts, "notop")
+	}
+	if q.PostedWithinDays > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", q.PostedWithinDays))
+	}
+	if len(q.ExcludeWords) > 0 {
+		words := append([]string(nil), q.ExcludeWords...)
+		sort.Strings(words)
+		parts = append(parts, "exclude "+strings.Join(words, " "))
+	}
+	if q.Match != "" {
+		parts = append(parts, "match "+q.Match)
+	}
+	if q.MinViews > 0 {
+		parts = append(parts, fmt.Sprintf("%dviews", q.MinViews))
+	}
+	if q.PhotoOnly {
+		parts = append(parts, "photo")
+	}
+	var name []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(strings.ToLower(p)); p != "" {
+			name = append(name, p)
+		}
+	}
+	return strings.Join(name, "-")
+}
@@ -0,0 +1,140 @@
+package history
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+func fixed(amount float64) bazos.Price {
+	return bazos.Price{Amount: amount, Currency: "EUR", Kind: bazos.PriceKindFixed}
+}
+
+func TestStoreRecordLoad(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1 := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+
+	ads := []bazos.Ad{
+		{ID: "1", Title: "a", Price: fixed(100), Views: 10},
+		{ID: "", Title: "no id"},
+	}
+	if err := store.Record("pracky", ads, t1); err != nil {
+		t.Fatal(err)
+	}
+	ads[0].Price = fixed(90)
+	if err := store.Record("pracky", ads, t2); err != nil {
+		t.Fatal(err)
+	}
+
+	obs, err := store.Load("pracky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) != 2 {
+		t.Fatalf("expected 2 observations, got %d", len(obs))
+	}
+	if !obs[0].Time.Equal(t1) || obs[1].Price != fixed(90) {
+		t.Errorf("unexpected observations: %+v", obs)
+	}
+
+	if obs, err := store.Load("missing"); err != nil || obs != nil {
+		t.Errorf("expected no observations for missing series, got %v, %v", obs, err)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	day := 24 * time.Hour
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	obs := []Observation{
+		// ad 1: active, price dropped once, 20 views/day
+		{Time: t0, AdID: "1", Price: fixed(200), Views: 0},
+		{Time: t0.Add(day), AdID: "1", Price: fixed(150), Views: 20},
+		{Time: t0.Add(2 * day), AdID: "1", Price: fixed(150), Views: 40},
+		// ad 2: removed after 1 day, posted a day before first seen
+		{Time: t0, AdID: "2", Price: fixed(100), Posted: t0.Add(-day)},
+		{Time: t0.Add(day), AdID: "2", Price: fixed(100)},
+		// ad 3: active, free
+		{Time: t0.Add(2 * day), AdID: "3", Price: bazos.Price{Kind: bazos.PriceKindFree}},
+		// ad 4: active, price in text without amount
+		{Time: t0.Add(2 * day), AdID: "4", Price: bazos.Price{Kind: bazos.PriceKindInText}},
+		// ad 5: active
+		{Time: t0.Add(2 * day), AdID: "5", Price: fixed(300)},
+	}
+
+	stats := Compute(obs)
+	if stats.Ads != 5 || stats.Active != 4 || stats.Observations != len(obs) {
+		t.Errorf("unexpected counts: ads=%d active=%d obs=%d", stats.Ads, stats.Active, stats.Observations)
+	}
+	wantPrices := PriceStats{Count: 3, Min: 0, P10: 30, P25: 75, Median: 150, P75: 225, P90: 270, Max: 300}
+	if stats.Prices != wantPrices {
+		t.Errorf("unexpected price stats:\n got %+v\nwant %+v", stats.Prices, wantPrices)
+	}
+	if stats.MedianTimeOnMarket != 2*day {
+		t.Errorf("expected median time on market 48h, got %v", stats.MedianTimeOnMarket)
+	}
+	// ad 1 has 20 views/day, ad 2 has no new views
+	if stats.MedianViewsPerDay != 10 {
+		t.Errorf("expected median views per day 10, got %v", stats.MedianViewsPerDay)
+	}
+	if stats.PriceDrops != 1 || stats.PriceDropRate != 0.2 {
+		t.Errorf("expected 1 price drop (rate 0.2), got %d (%v)", stats.PriceDrops, stats.PriceDropRate)
+	}
+	for _, as := range stats.AdStats {
+		if as.AdID == "1" && math.Abs(as.PricePercentile-50) > 1e-9 {
+			t.Errorf("expected ad 1 price percentile 50, got %v", as.PricePercentile)
+		}
+		if as.AdID == "2" && as.Active {
+			t.Error("expected ad 2 not active")
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{40, 10, 30, 20}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 10},
+		{50, 25},
+		{100, 40},
+		{25, 17.5},
+	}
+	for _, test := range tests {
+		if got := Percentile(values, test.p); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Percentile(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("expected 0 for empty values, got %v", got)
+	}
+}
+
+func TestSeriesName(t *testing.T) {
+	q := bazos.SearchQuery{
+		Query:   "Electrolux",
+		Section: &bazos.AdSection{Section: "elektro", Category: "pracky"},
+		PriceTo: 300,
+	}
+	if got, want := SeriesName(q), "sk-elektro-pracky-electrolux-0-300"; got != want {
+		t.Errorf("expected series %q, got %q", want, got)
+	}
+
+	q.Sort = bazos.SortPriceAsc
+	q.ExcludeTop = true
+	q.PostedWithinDays = 7
+	q.ExcludeWords = []string{"vadná", "diely"}
+	q.Match = "EW6"
+	q.MinViews = 10
+	q.PhotoOnly = true
+	want := "sk-elektro-pracky-electrolux-0-300-price-asc-notop-7d-exclude diely vadná-match ew6-10views-photo"
+	if got := SeriesName(q); got != want {
+		t.Errorf("expected series %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,212 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats are market statistics computed from observations.
+type Stats struct {
+	Ads          int
+	Observations int
+	// Active is the number of ads seen in the latest observation.
+	Active int
+	// Prices are statistics of the latest known prices of active ads.
+	Prices PriceStats
+	// MedianTimeOnMarket is the median time between posting (or first observation) and
+	// the last observation of ads no longer active.
+	MedianTimeOnMarket time.Duration
+	// MedianViewsPerDay is the median view velocity of ads observed more than once.
+	MedianViewsPerDay float64
+	// PriceDropRate is the fraction of ads whose price dropped at least once.
+	PriceDropRate float64
+	PriceDrops    int
+
+	AdStats []AdStats
+}
+
+// PriceStats are statistics of prices.
+type PriceStats struct {
+	Count  int
+	Min    float64
+	P10    float64
+	P25    float64
+	Median float64
+	P75    float64
+	P90    float64
+	Max    float64
+}
+
+// AdStats are statistics of a single ad.
+type AdStats struct {
+	AdID          string
+	Title         string
+	Price         float64
+	HasPrice      bool
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	Active        bool
+	TimeOnMarket  time.Duration
+	ViewsPerDay   float64
+	PriceDrops    int
+	PriceIncrease int
+	// PricePercentile is the percentile rank (0-100) of the ad price among active ads.
+	PricePercentile float64
+}
+
+// Compute computes statistics from observations.
+func Compute(observations []Observation) Stats {
+	stats := Stats{Observations: len(observations)}
+	if len(observations) == 0 {
+		return stats
+	}
+
+	byAd := make(map[string][]Observation)
+	var order []string
+	var latest time.Time
+	for _, o := range observations {
+		if _, ok := byAd[o.AdID]; !ok {
+			order = append(order, o.AdID)
+		}
+		byAd[o.AdID] = append(byAd[o.AdID], o)
+		if o.Time.After(latest) {
+			latest = o.Time
+		}
+	}
+	stats.Ads = len(byAd)
+
+	var (
+		prices      []float64
+		timesOnMkt  []float64
+		viewsPerDay []float64
+		dropped     int
+	)
+	for _, id := range order {
+		obs := byAd[id]
+		sort.SliceStable(obs, func(i, j int) bool { return obs[i].Time.Before(obs[j].Time) })
+		first, last := obs[0], obs[len(obs)-1]
+
+		as := AdStats{
+			AdID:      id,
+			Title:     last.Title,
+			FirstSeen: first.Time,
+			LastSeen:  last.Time,
+			Active:    last.Time.Equal(latest),
+		}
+		if v, ok := last.Price.Value(); ok {
+			as.Price, as.HasPrice = v, true
+		}
+
+		start := first.Time
+		if !first.Posted.IsZero() && first.Posted.Before(start) {
+			start = first.Posted
+		}
+		as.TimeOnMarket = last.Time.Sub(start)
+		if !as.Active {
+			timesOnMkt = append(timesOnMkt, float64(as.TimeOnMarket))
+		}
+
+		if days := last.Time.Sub(first.Time).Hours() / 24; days > 0 && last.Views >= first.Views {
+			as.ViewsPerDay = float64(last.Views-first.Views) / days
+			viewsPerDay = append(viewsPerDay, as.ViewsPerDay)
+		}
+
+		var prev *float64
+		for _, o := range obs {
+			v, ok := o.Price.Value()
+			if !ok {
+				continue
+			}
+			if prev != nil {
+				if v < *prev {
+					as.PriceDrops++
+				} else if v > *prev {
+					as.PriceIncrease++
+				}
+			}
+			prev = &v
+		}
+		if as.PriceDrops > 0 {
+			dropped++
+		}
+		stats.PriceDrops += as.PriceDrops
+
+		if as.Active {
+			stats.Active++
+			if as.HasPrice {
+				prices = append(prices, as.Price)
+			}
+		}
+		stats.AdStats = append(stats.AdStats, as)
+	}
+
+	sort.Float64s(prices)
+	stats.Prices = computePriceStats(prices)
+	stats.MedianTimeOnMarket = time.Duration(Percentile(timesOnMkt, 50))
+	stats.MedianViewsPerDay = Percentile(viewsPerDay, 50)
+	stats.PriceDropRate = float64(dropped) / float64(stats.Ads)
+
+	for i := range stats.AdStats {
+		as := &stats.AdStats[i]
+		if as.Active && as.HasPrice {
+			as.PricePercentile = PercentileRank(prices, as.Price)
+		}
+	}
+
+	return stats
+}
+
+func computePriceStats(sorted []float64) PriceStats {
+	if len(sorted) == 0 {
+		return PriceStats{}
+	}
+	return PriceStats{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		P10:    Percentile(sorted, 10),
+		P25:    Percentile(sorted, 25),
+		Median: Percentile(sorted, 50),
+		P75:    Percentile(sorted, 75),
+		P90:    Percentile(sorted, 90),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// Percentile returns p-th percentile (0-100) of values using linear interpolation.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo < 0 {
+		return sorted[0]
+	}
+	if hi >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// PercentileRank returns percentage (0-100) of values lower than v, counting equal values as half.
+func PercentileRank(values []float64, v float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var below, equal int
+	for _, x := range values {
+		if x < v {
+			below++
+		} else if x == v {
+			equal++
+		}
+	}
+	return (float64(below) + float64(equal)/2) / float64(len(values)) * 100
+}
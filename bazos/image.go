@@ -0,0 +1,25 @@
+package bazos
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+var thumbnailPathRegexp = regexp.MustCompile(`/img/(\d+)t/`)
+
+// FullImageURL returns URL of the full resolution image for thumbnail URL u,
+// e.g. ".../img/1t/567/151234567.jpg" becomes ".../img/1/567/151234567.jpg".
+// Other URLs are returned unchanged.
+func FullImageURL(u string) string {
+	return thumbnailPathRegexp.ReplaceAllString(u, "/img/$1/")
+}
+
+// FetchImage downloads image at u respecting the crawl policy.
+func (c *Client) FetchImage(ctx context.Context, u string) ([]byte, error) {
+	body, _, err := c.fetch(ctx, u, pageImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	return body, nil
+}
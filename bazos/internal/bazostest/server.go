@@ -0,0 +1,117 @@
+// Package bazostest provides a local HTTP server serving recorded bazos pages for tests.
+package bazostest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server serves fixture files for requests to any host.
+type Server struct {
+	*httptest.Server
+
+	dir string
+
+	mu       sync.Mutex
+	routes   map[string]string
+	requests []*http.Request
+}
+
+// NewServer starts a new Server serving files from dir. The server is closed when the test ends.
+func NewServer(tb testing.TB, dir string) *Server {
+	tb.Helper()
+	s := &Server{
+		dir:    dir,
+		routes: make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Handle registers fixture file served for route. The route is either a path (e.g. "/pracky/")
+// matching any host, or a host followed by path (e.g. "elektro.bazos.test/pracky/").
+// Query is ignored unless the route contains it.
+func (s *Server) Handle(route, file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[route] = file
+}
+
+// Requests returns all requests received by the server.
+func (s *Server) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+// HTTPClient returns a client that sends requests for any host to the server.
+func (s *Server) HTTPClient() *http.Client {
+	addr := s.Listener.Addr().String()
+	dialer := &net.Dialer{}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	file, ok := s.lookup(r)
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, file))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType(file))
+	_, _ = w.Write(data)
+}
+
+func (s *Server) lookup(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	candidates := []string{
+		host + r.URL.RequestURI(),
+		host + r.URL.Path,
+		r.URL.RequestURI(),
+		r.URL.Path,
+	}
+	for _, c := range candidates {
+		if file, ok := s.routes[c]; ok {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+func contentType(file string) string {
+	switch {
+	case strings.HasSuffix(file, ".html"):
+		return "text/html; charset=utf-8"
+	case strings.HasSuffix(file, ".jpg"):
+		return "image/jpeg"
+	case strings.HasSuffix(file, ".png"):
+		return "image/png"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
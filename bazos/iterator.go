@@ -0,0 +1,259 @@
+package bazos
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxPages is the number of listing pages fetched when ListOptions.MaxPages is zero.
+const DefaultMaxPages = 10
+
+// ListOptions controls pagination of ad listings.
+type ListOptions struct {
+	// MaxPages limits the number of fetched pages. Zero means DefaultMaxPages, negative means no limit.
+	MaxPages int
+	// MaxResults limits the number of returned ads. Zero means no limit.
+	MaxResults int
+	// CollapseReposts returns only the original of ads that duplicate each other, the one with
+	// the lowest ID as marked by MarkReposts with DefaultDedupOptions. All pages within MaxPages
+	// are fetched before the first ad is returned. Images are not compared.
+	CollapseReposts bool
+}
+
+func (o ListOptions) maxPages() int {
+	if o.MaxPages == 0 {
+		return DefaultMaxPages
+	}
+	return o.MaxPages
+}
+
+// AdIterator iterates over ads from listing pages, fetching next pages on demand.
+//
+//	it := client.IterAds(ctx, query, bazos.ListOptions{MaxResults: 50})
+//	for it.Next() {
+//		ad := it.Ad()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type AdIterator struct {
+	ctx    context.Context
+	client *Client
+	opts   ListOptions
+	filter func(Ad) bool
+	// filtered is set when the query filters ads locally
+	filtered bool
+
+	base      *url.URL
+	nextUrl   string
+	page      *AdListingsPage
+	pages     int
+	buf       []Ad
+	ad        Ad
+	count     int
+	err       error
+	truncated bool
+	done      bool
+	collapsed bool
+	reposts   []Ad
+}
+
+// IterAds returns iterator over ads matching the query.
+func (c *Client) IterAds(ctx context.Context, q SearchQuery, opts ListOptions) *AdIterator {
+	_, domain, err := c.searchSite(q)
+	if err != nil {
+		return &AdIterator{err: err, done: true}
+	}
+	u, err := q.toUrl(c.scheme, domain)
+	if err != nil {
+		return &AdIterator{err: err, done: true}
+	}
+	filter, err := q.Filter(c.now())
+	if err != nil {
+		return &AdIterator{err: err, done: true}
+	}
+	it := c.IterListings(ctx, u.String(), opts)
+	it.filter = filter
+	it.filtered = filter != nil
+	return it
+}
+
+// IterListings returns iterator over ads listed at u and its next pages.
+func (c *Client) IterListings(ctx context.Context, u string, opts ListOptions) *AdIterator {
+	base, err := url.Parse(u)
+	if err != nil {
+		return &AdIterator{err: err, done: true}
+	}
+	return &AdIterator{
+		ctx:     ctx,
+		client:  c,
+		opts:    opts,
+		base:    base,
+		nextUrl: base.String(),
+	}
+}
+
+// Next advances the iterator to the next ad, fetching next page if needed.
+// It returns false when there are no more ads, the limits were reached or an error occurred.
+func (it *AdIterator) Next() bool {
+	if it.opts.CollapseReposts && !it.collapsed {
+		it.collapse()
+	}
+	for !it.done {
+		if it.opts.MaxResults > 0 && it.count >= it.opts.MaxResults {
+			// more ads are available only if a buffered ad passes the filter or a page remains
+			it.truncated = it.nextUrl != "" || it.hasBuffered()
+			it.done = true
+			break
+		}
+		if len(it.buf) > 0 {
+			ad := it.buf[0]
+			it.buf = it.buf[1:]
+			if it.filter != nil && !it.filter(ad) {
+				continue
+			}
+			it.ad = ad
+			it.count++
+			return true
+		}
+		if !it.fetchNext() {
+			it.done = true
+		}
+	}
+	return false
+}
+
+// hasBuffered reports whether any buffered ad passes the filter.
+func (it *AdIterator) hasBuffered() bool {
+	for _, ad := range it.buf {
+		if it.filter == nil || it.filter(ad) {
+			return true
+		}
+	}
+	return false
+}
+
+// collapse fetches all pages within MaxPages and buffers the ads passing the filter with
+// reposts collapsed into their originals.
+func (it *AdIterator) collapse() {
+	it.collapsed = true
+	var ads []Ad
+	for !it.done {
+		for _, ad := range it.buf {
+			if it.filter == nil || it.filter(ad) {
+				ads = append(ads, ad)
+			}
+		}
+		it.buf = nil
+		if !it.fetchNext() {
+			break
+		}
+	}
+	if it.err != nil {
+		it.done = true
+		return
+	}
+
+	MarkReposts(ads, DefaultDedupOptions)
+	for _, ad := range ads {
+		if ad.RepostOf != "" {
+			logrus.Debugf("collapsing ad %s as repost of %s", ad.ID, ad.RepostOf)
+			it.reposts = append(it.reposts, ad)
+			continue
+		}
+		it.buf = append(it.buf, ad)
+	}
+	// buffered ads are already filtered
+	it.filter = nil
+}
+
+func (it *AdIterator) fetchNext() bool {
+	if it.nextUrl == "" {
+		return false
+	}
+	if max := it.opts.maxPages(); max > 0 && it.pages >= max {
+		it.truncated = true
+		return false
+	}
+
+	page, err := it.client.getAdListingsPage(it.ctx, it.nextUrl)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	return it.setPage(page)
+}
+
+// setPage sets page as the last fetched page and buffers its ads.
+func (it *AdIterator) setPage(page *AdListingsPage) bool {
+	it.page = page
+	it.pages++
+	it.buf = page.AdListings
+
+	it.nextUrl = ""
+	if page.NextPage != "" {
+		next, err := it.base.Parse(page.NextPage)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.nextUrl = next.String()
+	}
+	return true
+}
+
+// Ad returns the current ad.
+func (it *AdIterator) Ad() Ad {
+	return it.ad
+}
+
+// Page returns the last fetched listing page with its Total/From/To metadata.
+func (it *AdIterator) Page() *AdListingsPage {
+	return it.page
+}
+
+// Pages returns the number of fetched pages.
+func (it *AdIterator) Pages() int {
+	return it.pages
+}
+
+// Err returns the error that stopped the iteration.
+func (it *AdIterator) Err() error {
+	return it.err
+}
+
+// Truncated reports whether the iteration was cut off by MaxPages or MaxResults
+// while more ads were available.
+func (it *AdIterator) Truncated() bool {
+	return it.truncated
+}
+
+// Filtered reports whether ads are filtered locally by the query or collapsed reposts, so
+// Page().Total, the number of ads reported by the site, is not the number of returned ads.
+func (it *AdIterator) Filtered() bool {
+	return it.filtered || it.opts.CollapseReposts
+}
+
+// Reposts returns ads left out by CollapseReposts, with RepostOf set to the ID of the
+// returned original.
+func (it *AdIterator) Reposts() []Ad {
+	return it.reposts
+}
+
+// Stop terminates the iteration, no more pages are fetched.
+func (it *AdIterator) Stop() {
+	it.done = true
+	it.buf = nil
+}
+
+// All collects all remaining ads.
+func (it *AdIterator) All() ([]Ad, error) {
+	var ads []Ad
+	for it.Next() {
+		ads = append(ads, it.Ad())
+	}
+	return ads, it.Err()
+}
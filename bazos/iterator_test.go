@@ -0,0 +1,134 @@
+package bazos
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAdIterator(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          ListOptions
+		wantAds       int
+		wantPages     int
+		wantTruncated bool
+	}{
+		{"defaults", ListOptions{}, 5, 2, false},
+		{"unlimited pages", ListOptions{MaxPages: -1}, 5, 2, false},
+		{"max pages", ListOptions{MaxPages: 1}, 3, 1, true},
+		{"max results within page", ListOptions{MaxResults: 2}, 2, 1, true},
+		{"max results across pages", ListOptions{MaxResults: 4}, 4, 2, true},
+		{"max results equal total", ListOptions{MaxResults: 5}, 5, 2, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, client := newTestServer(t)
+			srv.Handle("/search.php", "search_electrolux.html")
+			srv.Handle("/search.php?hledat=electrolux&rubriky=elektro&crz=3", "search_electrolux_3.html")
+
+			it := client.IterAds(context.Background(), SearchQuery{Query: "electrolux"}, test.opts)
+			ads, err := it.All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ads) != test.wantAds {
+				t.Errorf("expected %d ads, got %d", test.wantAds, len(ads))
+			}
+			if it.Pages() != test.wantPages {
+				t.Errorf("expected %d pages, got %d", test.wantPages, it.Pages())
+			}
+			if it.Truncated() != test.wantTruncated {
+				t.Errorf("expected truncated %v, got %v", test.wantTruncated, it.Truncated())
+			}
+			if n := len(srv.Requests()); n != test.wantPages {
+				t.Errorf("expected %d requests, got %d", test.wantPages, n)
+			}
+			if it.Filtered() {
+				t.Error("expected unfiltered iterator")
+			}
+		})
+	}
+}
+
+func TestAdIteratorFilteredLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          ListOptions
+		wantAds       int
+		wantTruncated bool
+	}{
+		// the last ad on the last page is filtered out
+		{"limit at last match", ListOptions{MaxResults: 3}, 3, false},
+		{"limit before last match", ListOptions{MaxResults: 2}, 2, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, client := newTestServer(t)
+			srv.Handle("/search.php", "search_electrolux.html")
+			srv.Handle("/search.php?hledat=electrolux&rubriky=elektro&crz=3", "search_electrolux_3.html")
+
+			q := SearchQuery{Query: "electrolux", PriceFrom: 100}
+			it := client.IterAds(context.Background(), q, test.opts)
+			ads, err := it.All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ads) != test.wantAds {
+				t.Errorf("expected %d ads, got %d", test.wantAds, len(ads))
+			}
+			if it.Truncated() != test.wantTruncated {
+				t.Errorf("expected truncated %v, got %v", test.wantTruncated, it.Truncated())
+			}
+			if !it.Filtered() {
+				t.Error("expected filtered iterator")
+			}
+		})
+	}
+}
+
+func TestAdIteratorStop(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_electrolux.html")
+
+	it := client.IterAds(context.Background(), SearchQuery{Query: "electrolux"}, ListOptions{})
+	if !it.Next() {
+		t.Fatalf("expected first ad, err: %v", it.Err())
+	}
+	if page := it.Page(); page.Total != 5 || page.From != 1 || page.To != 3 {
+		t.Errorf("unexpected page metadata: total=%d from=%d to=%d", page.Total, page.From, page.To)
+	}
+	it.Stop()
+	if it.Next() {
+		t.Error("expected no more ads after Stop")
+	}
+	if it.Truncated() {
+		t.Error("expected stopped iterator not to be truncated")
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestAdIteratorCollapseReposts(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/search.php", "search_reposts.html")
+
+	// the repost is listed before its original
+	it := client.IterAds(context.Background(), SearchQuery{Query: "bicykel"}, ListOptions{CollapseReposts: true})
+	ads, err := it.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, ad := range ads {
+		ids = append(ids, ad.ID)
+	}
+	if got := strings.Join(ids, " "); got != "152000000 150000000" {
+		t.Errorf("expected originals 152000000 150000000, got %s", got)
+	}
+	reposts := it.Reposts()
+	if len(reposts) != 1 || reposts[0].ID != "153000000" || reposts[0].RepostOf != "150000000" {
+		t.Errorf("expected 153000000 collapsed into 150000000, got %+v", reposts)
+	}
+}
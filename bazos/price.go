@@ -0,0 +1,174 @@
+package bazos
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PriceKind describes the kind of ad price.
+type PriceKind int
+
+const (
+	PriceKindUnknown PriceKind = iota
+	PriceKindFixed
+	PriceKindFree
+	PriceKindNegotiable
+	PriceKindInText
+)
+
+var priceKindNames = map[PriceKind]string{
+	PriceKindUnknown:    "unknown",
+	PriceKindFixed:      "fixed",
+	PriceKindFree:       "free",
+	PriceKindNegotiable: "negotiable",
+	PriceKindInText:     "in-text",
+}
+
+func (k PriceKind) String() string {
+	if n, ok := priceKindNames[k]; ok {
+		return n
+	}
+	return fmt.Sprintf("PriceKind(%d)", int(k))
+}
+
+func (k PriceKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *PriceKind) UnmarshalText(text []byte) error {
+	for kind, name := range priceKindNames {
+		if string(text) == name {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid price kind %q", text)
+}
+
+// Price is a price of an ad.
+type Price struct {
+	// Amount is the price amount. For in-text prices it is the amount found in description, if any.
+	Amount   float64 `json:",omitempty" yaml:",omitempty"`
+	Currency string  `json:",omitempty" yaml:",omitempty"`
+	Kind     PriceKind
+}
+
+// Value returns the price amount and whether it is known.
+func (p Price) Value() (float64, bool) {
+	switch p.Kind {
+	case PriceKindFixed:
+		return p.Amount, true
+	case PriceKindFree:
+		return 0, true
+	case PriceKindInText:
+		return p.Amount, p.Amount > 0
+	default:
+		return 0, false
+	}
+}
+
+func (p Price) String() string {
+	switch p.Kind {
+	case PriceKindFixed:
+		return formatAmount(p.Amount, p.Currency)
+	case PriceKindInText:
+		if p.Amount > 0 {
+			return fmt.Sprintf("%s (in text)", formatAmount(p.Amount, p.Currency))
+		}
+		return "in text"
+	default:
+		return p.Kind.String()
+	}
+}
+
+func formatAmount(amount float64, currency string) string {
+	s := strconv.FormatFloat(amount, 'f', -1, 64)
+	if currency == "" {
+		return s
+	}
+	return s + " " + currency
+}
+
+// parsePrice parses price text displayed on the site.
+func parsePrice(text string, site *Site) (Price, error) {
+	price := Price{Currency: site.Currency}
+	priceText := strings.NewReplacer(" ", "", "\u00a0", "").Replace(text)
+	priceText = strings.TrimSuffix(priceText, strings.ReplaceAll(site.CurrencySymbol, " ", ""))
+	switch {
+	case priceText == "":
+		return price, fmt.Errorf("empty price")
+	case matchesText(priceText, site.PriceFree):
+		price.Kind = PriceKindFree
+		return price, nil
+	case matchesText(priceText, site.PriceInText):
+		price.Kind = PriceKindInText
+		return price, nil
+	}
+	for _, marker := range site.PriceNegotiable {
+		if matchesText(priceText, marker) {
+			price.Kind = PriceKindNegotiable
+			return price, nil
+		}
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(priceText, ",", "."), 64)
+	if err != nil {
+		return price, err
+	}
+	price.Amount = amount
+	price.Kind = PriceKindFixed
+	return price, nil
+}
+
+// extractPrice finds the first amount followed by the site currency in text.
+func extractPrice(text string, sel *Selectors) (float64, bool) {
+	m := sel.priceRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false
+	}
+	num := strings.NewReplacer(" ", "", "\u00a0", "", ".", "").Replace(m[1])
+	amount, err := strconv.ParseFloat(num, 64)
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+// FilterAds returns ads for which keep returns true.
+func FilterAds(ads []Ad, keep func(Ad) bool) []Ad {
+	var filtered []Ad
+	for _, ad := range ads {
+		if keep(ad) {
+			filtered = append(filtered, ad)
+		}
+	}
+	return filtered
+}
+
+// PriceBetween returns filter keeping ads with known price in range [min, max].
+// Zero max means no upper bound. Ads with unknown price are kept if keepUnknown is true.
+func PriceBetween(min, max float64, keepUnknown bool) func(Ad) bool {
+	return func(ad Ad) bool {
+		v, ok := ad.Price.Value()
+		if !ok {
+			return keepUnknown
+		}
+		return v >= min && (max <= 0 || v <= max)
+	}
+}
+
+// SortByPrice sorts ads by known price, ads with unknown price are placed last.
+func SortByPrice(ads []Ad, desc bool) {
+	sort.SliceStable(ads, func(i, j int) bool {
+		vi, oki := ads[i].Price.Value()
+		vj, okj := ads[j].Price.Value()
+		if oki != okj {
+			return oki
+		}
+		if desc {
+			return vi > vj
+		}
+		return vi < vj
+	})
+}
@@ -1,31 +1,177 @@
 package bazos
 
 import (
+	"context"
 	"fmt"
 	"html"
 	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type SearchQuery struct {
+	// Site is the country code of the site to search, e.g. "cz". Client site is used if empty.
+	Site      string `json:",omitempty" yaml:",omitempty"`
 	Query     string
 	Section   *AdSection
 	Location  string
 	Vicinity  int
 	PriceFrom int
 	PriceTo   int
+
+	// Sort is the sort order of results provided by the site.
+	Sort SortOrder `json:",omitempty" yaml:",omitempty"`
+
+	// Following filters are applied client-side.
+
+	// ExcludeTop excludes promoted (TOP) ads.
+	ExcludeTop bool `json:",omitempty" yaml:",omitempty"`
+	// PostedWithinDays keeps ads posted (or bumped) in the last N days. Zero means no limit.
+	PostedWithinDays int `json:",omitempty" yaml:",omitempty"`
+	// ExcludeWords excludes ads with any of the words in title or description, case-insensitive.
+	ExcludeWords []string `json:",omitempty" yaml:",omitempty"`
+	// Match is a regular expression the title or description must match.
+	Match string `json:",omitempty" yaml:",omitempty"`
+	// MinViews keeps ads with at least the number of views.
+	MinViews int `json:",omitempty" yaml:",omitempty"`
+	// PhotoOnly keeps ads with photo.
+	PhotoOnly bool `json:",omitempty" yaml:",omitempty"`
 }
 
-func (q SearchQuery) Search() ([]Ad, error) {
-	u, err := q.toUrl()
-	if err != nil {
-		return nil, err
+// SortOrder is the sort order of search results.
+type SortOrder string
+
+const (
+	SortNewest    SortOrder = ""
+	SortPriceAsc  SortOrder = "price-asc"
+	SortPriceDesc SortOrder = "price-desc"
+)
+
+// sortOrderParams maps sort orders to values of the "order" search parameter.
+var sortOrderParams = map[SortOrder]string{
+	SortNewest:    "",
+	SortPriceAsc:  "1",
+	SortPriceDesc: "2",
+}
+
+// Validate checks the query against the site, i.e. that the section exists,
+// the location is a valid postcode and the price range and vicinity are sane.
+func (q SearchQuery) Validate(site *Site) error {
+	if q.Site != "" {
+		s, err := LookupSite(q.Site)
+		if err != nil {
+			return err
+		}
+		site = s
+	}
+	if q.Section != nil && q.Section.Section != "" && !site.HasSection(q.Section.Section) {
+		return fmt.Errorf("unknown section %q on %s, available sections: %s",
+			q.Section.Section, site.Domain, strings.Join(site.Sections, ", "))
+	}
+	if q.Section != nil && q.Section.Section == "" && q.Section.Category != "" {
+		return fmt.Errorf("category %q requires section", q.Section.Category)
+	}
+	if q.Location != "" {
+		if _, err := site.NormalizePostCode(q.Location); err != nil {
+			return err
+		}
+	}
+	if q.Vicinity < 0 {
+		return fmt.Errorf("invalid vicinity %d, must not be negative", q.Vicinity)
+	}
+	if q.PriceFrom < 0 || q.PriceTo < 0 {
+		return fmt.Errorf("invalid price range %d-%d, prices must not be negative", q.PriceFrom, q.PriceTo)
+	}
+	if q.PriceTo > 0 && q.PriceFrom > q.PriceTo {
+		return fmt.Errorf("invalid price range %d-%d, minimum is greater than maximum", q.PriceFrom, q.PriceTo)
+	}
+	if _, ok := sortOrderParams[q.Sort]; !ok {
+		return fmt.Errorf("invalid sort order %q, expected %q or %q", q.Sort, SortPriceAsc, SortPriceDesc)
+	}
+	if q.PostedWithinDays < 0 || q.MinViews < 0 {
+		return fmt.Errorf("invalid filter, days and views must not be negative")
 	}
+	if _, err := q.Filter(time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
 
-	logrus.Debugf("searching: %+v", q)
+// Filter returns filter of ads applying the price range and the client-side filters of the query,
+// now is the time used for PostedWithinDays. It returns nil filter if the query has no such filters.
+func (q SearchQuery) Filter(now time.Time) (func(Ad) bool, error) {
+	var filters []func(Ad) bool
+	if q.PriceFrom > 0 || q.PriceTo > 0 {
+		filters = append(filters, PriceBetween(float64(q.PriceFrom), float64(q.PriceTo), true))
+	}
+	if q.ExcludeTop {
+		filters = append(filters, func(ad Ad) bool { return !ad.Top })
+	}
+	if q.PostedWithinDays > 0 {
+		filters = append(filters, func(ad Ad) bool {
+			listed := ad.Listed()
+			if listed.IsZero() {
+				// keep ads with unknown date
+				return true
+			}
+			// days are counted in the time zone of the ad date
+			y, m, d := now.In(listed.Location()).Date()
+			cutoff := time.Date(y, m, d, 0, 0, 0, 0, listed.Location()).AddDate(0, 0, -q.PostedWithinDays)
+			return !listed.Before(cutoff)
+		})
+	}
+	if len(q.ExcludeWords) > 0 {
+		var words []string
+		for _, w := range q.ExcludeWords {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				words = append(words, w)
+			}
+		}
+		filters = append(filters, func(ad Ad) bool {
+			text := strings.ToLower(ad.Title + "\n" + ad.Description)
+			for _, w := range words {
+				if strings.Contains(text, w) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	if q.Match != "" {
+		re, err := regexp.Compile(q.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match expression: %w", err)
+		}
+		filters = append(filters, func(ad Ad) bool {
+			return re.MatchString(ad.Title) || re.MatchString(ad.Description)
+		})
+	}
+	if q.MinViews > 0 {
+		filters = append(filters, func(ad Ad) bool { return ad.Views >= q.MinViews })
+	}
+	if q.PhotoOnly {
+		filters = append(filters, Ad.HasPhoto)
+	}
 
-	return GetAdListings(u.String())
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return func(ad Ad) bool {
+		for _, keep := range filters {
+			if !keep(ad) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// Search returns ads matching the query using DefaultClient.
+func (q SearchQuery) Search() ([]Ad, error) {
+	return DefaultClient.Search(context.Background(), q)
 }
 
 func (q SearchQuery) queryValues() url.Values {
@@ -44,53 +190,37 @@ func (q SearchQuery) queryValues() url.Values {
 	if q.PriceTo > 0 {
 		v.Set("cenado", fmt.Sprint(q.PriceTo))
 	}
+	if order := sortOrderParams[q.Sort]; order != "" {
+		v.Set("order", order)
+	}
 	return v
 }
 
-func (q SearchQuery) toUrl() (*url.URL, error) {
+func (q SearchQuery) toUrl(scheme, domain string) (*url.URL, error) {
 	qvals := q.queryValues().Encode()
-	urlPath := DefaultDomain + "/search.php"
+	urlPath := domain + "/search.php"
 	if q.Section != nil && q.Section.Section != "" {
 		if q.Section.Category == "" {
-			urlPath = fmt.Sprintf("%s.%s/search.php", q.Section.Section, DefaultDomain)
+			urlPath = fmt.Sprintf("%s.%s/search.php", q.Section.Section, domain)
 		} else {
-			urlPath = fmt.Sprintf("%s.%s/%s/", q.Section.Section, DefaultDomain, q.Section.Category)
+			urlPath = fmt.Sprintf("%s.%s/%s/", q.Section.Section, domain, q.Section.Category)
 		}
 	}
-	u, err := url.Parse("https://" + urlPath + "?" + qvals)
+	u, err := url.Parse(scheme + "://" + urlPath + "?" + qvals)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
+// Search returns ads matching the query text using DefaultClient.
 func Search(query string) ([]Ad, error) {
-	/*queryEsc := html.EscapeString(query)
-	  domain := DefaultDomain
-	  rubriky := AnySection
-	  hlokalita := ""
-	  vicinity := DefaultVicinity
-	  priceFrom := ""
-	  priceTo := ""
-
-	  u := fmt.Sprintf("https://%s/search.php?hledat=%s&rubriky=%v&hlokalita=%v&humkreis=%v&cenaod=%v&cenado=%v",
-	  	domain, queryEsc, rubriky, hlokalita, vicinity, priceFrom, priceTo)*/
-
 	logrus.Debugf("searching for %q", query)
 
 	search := SearchQuery{
 		Query:    query,
 		Vicinity: DefaultVicinity,
 	}
-	/*u, err := search.toUrl()
-	  if err != nil {
-	  	return nil, err
-	  }
-
-	  logrus.Infof("searching %q", query)
-	  logrus.Debugf("search URL: %v", u)
-
-	  return GetAdListings(u.String())*/
 
-	return search.Search()
+	return DefaultClient.Search(context.Background(), search)
 }
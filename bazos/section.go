@@ -70,3 +70,147 @@ var sectionsNames = map[string]string{
 	"zvierata":  "Zvieratá",
 	"ostatne":   "Ostatné",
 }
+
+var sectionsListCZ = []string{
+	AnySection,
+	"auto",
+	"deti",
+	"dum",
+	"elektro",
+	"foto",
+	"hudba",
+	"knihy",
+	"mobil",
+	"motorky",
+	"nabytek",
+	"obleceni",
+	"pc",
+	"prace",
+	"reality",
+	"sluzby",
+	"stroje",
+	"sport",
+	"vstupenky",
+	"zvirata",
+	"ostatni",
+}
+
+var sectionsNamesCZ = map[string]string{
+	AnySection:  "Všechny rubriky",
+	"auto":      "Auto",
+	"deti":      "Děti",
+	"dum":       "Dům a zahrada",
+	"elektro":   "Elektro",
+	"foto":      "Foto",
+	"hudba":     "Hudba",
+	"knihy":     "Knihy",
+	"mobil":     "Mobily",
+	"motorky":   "Motorky",
+	"nabytek":   "Nábytek",
+	"obleceni":  "Oblečení",
+	"pc":        "PC",
+	"prace":     "Práce",
+	"reality":   "Reality",
+	"sluzby":    "Služby",
+	"stroje":    "Stroje",
+	"sport":     "Sport",
+	"vstupenky": "Vstupenky",
+	"zvirata":   "Zvířata",
+	"ostatni":   "Ostatní",
+}
+
+var sectionsListPL = []string{
+	AnySection,
+	"auto",
+	"dzieci",
+	"dom",
+	"elektro",
+	"foto",
+	"muzyka",
+	"ksiazki",
+	"telefony",
+	"motocykle",
+	"meble",
+	"odziez",
+	"pc",
+	"praca",
+	"nieruchomosci",
+	"uslugi",
+	"maszyny",
+	"sport",
+	"bilety",
+	"zwierzeta",
+	"inne",
+}
+
+var sectionsNamesPL = map[string]string{
+	AnySection:      "Wszystkie działy",
+	"auto":          "Motoryzacja",
+	"dzieci":        "Dzieci",
+	"dom":           "Dom i ogród",
+	"elektro":       "Elektronika",
+	"foto":          "Foto",
+	"muzyka":        "Muzyka",
+	"ksiazki":       "Książki",
+	"telefony":      "Telefony",
+	"motocykle":     "Motocykle",
+	"meble":         "Meble",
+	"odziez":        "Odzież",
+	"pc":            "Komputery",
+	"praca":         "Praca",
+	"nieruchomosci": "Nieruchomości",
+	"uslugi":        "Usługi",
+	"maszyny":       "Maszyny",
+	"sport":         "Sport",
+	"bilety":        "Bilety",
+	"zwierzeta":     "Zwierzęta",
+	"inne":          "Inne",
+}
+
+var sectionsListAT = []string{
+	AnySection,
+	"auto",
+	"kinder",
+	"haus",
+	"elektro",
+	"foto",
+	"musik",
+	"buecher",
+	"handy",
+	"motorrad",
+	"moebel",
+	"kleidung",
+	"pc",
+	"arbeit",
+	"immobilien",
+	"dienstleistungen",
+	"maschinen",
+	"sport",
+	"tickets",
+	"tiere",
+	"sonstiges",
+}
+
+var sectionsNamesAT = map[string]string{
+	AnySection:         "Alle Rubriken",
+	"auto":             "Auto",
+	"kinder":           "Kinder",
+	"haus":             "Haus und Garten",
+	"elektro":          "Elektro",
+	"foto":             "Foto",
+	"musik":            "Musik",
+	"buecher":          "Bücher",
+	"handy":            "Handys",
+	"motorrad":         "Motorrad",
+	"moebel":           "Möbel",
+	"kleidung":         "Kleidung",
+	"pc":               "PC",
+	"arbeit":           "Arbeit",
+	"immobilien":       "Immobilien",
+	"dienstleistungen": "Dienstleistungen",
+	"maschinen":        "Maschinen",
+	"sport":            "Sport",
+	"tickets":          "Tickets",
+	"tiere":            "Tiere",
+	"sonstiges":        "Sonstiges",
+}
@@ -0,0 +1,305 @@
+package bazos
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SelectorsVersion is the version of selector files supported by the parsers.
+const SelectorsVersion = 1
+
+//go:embed selectors.yaml
+var defaultSelectorsYAML []byte
+
+// Selectors are CSS selectors, patterns and layouts used by the parsers for a site.
+type Selectors struct {
+	Breadcrumbs     string            `yaml:"breadcrumbs"`
+	DateLayout      string            `yaml:"date_layout"`
+	DatePattern     string            `yaml:"date_pattern"`
+	PostCodePattern string            `yaml:"postcode_pattern"`
+	ViewsPattern    string            `yaml:"views_pattern"`
+	PricePattern    string            `yaml:"price_pattern"`
+	Listings        ListingsSelectors `yaml:"listings"`
+	Ad              AdSelectors       `yaml:"ad"`
+	Phone           PhoneSelectors    `yaml:"phone"`
+	Catalog         CatalogSelectors  `yaml:"catalog"`
+	Seller          SellerSelectors   `yaml:"seller"`
+
+	infoRegexp          *regexp.Regexp
+	idRegexp            *regexp.Regexp
+	dateRegexp          *regexp.Regexp
+	sellerRegexp        *regexp.Regexp
+	postCodeRegexp      *regexp.Regexp
+	viewsRegexp         *regexp.Regexp
+	priceRegexp         *regexp.Regexp
+	phoneRegexp         *regexp.Regexp
+	categoryPathRegexp  *regexp.Regexp
+	categoryCountRegexp *regexp.Regexp
+}
+
+// ListingsSelectors are selectors of ad listings pages. Selectors of ad fields are relative to Ad.
+type ListingsSelectors struct {
+	Info        string `yaml:"info"`
+	InfoPattern string `yaml:"info_pattern"`
+	CurrentPage string `yaml:"current_page"`
+	PageLinks   string `yaml:"page_links"`
+	Ad          string `yaml:"ad"`
+	AdLink      string `yaml:"ad_link"`
+	Title       string `yaml:"title"`
+	Image       string `yaml:"image"`
+	ImageAttr   string `yaml:"image_attr"`
+	Description string `yaml:"description"`
+	Date        string `yaml:"date"`
+	TopMarker   string `yaml:"top_marker"`
+	Location    string `yaml:"location"`
+	Views       string `yaml:"views"`
+	Price       string `yaml:"price"`
+}
+
+// AdSelectors are selectors of ad detail pages.
+type AdSelectors struct {
+	Title       string `yaml:"title"`
+	Date        string `yaml:"date"`
+	Description string `yaml:"description"`
+	DetailRows  string `yaml:"detail_rows"`
+	Images      string `yaml:"images"`
+	ImageAttr   string `yaml:"image_attr"`
+	IDLinks     string `yaml:"id_links"`
+	IDPattern   string `yaml:"id_pattern"`
+	PhoneLink   string `yaml:"phone_link"`
+}
+
+// PhoneSelectors are selectors of phone reveal responses.
+type PhoneSelectors struct {
+	Link    string `yaml:"link"`
+	Pattern string `yaml:"pattern"`
+}
+
+// CatalogSelectors are selectors of category links on section pages.
+type CatalogSelectors struct {
+	Links        string `yaml:"links"`
+	PathPattern  string `yaml:"path_pattern"`
+	CountPattern string `yaml:"count_pattern"`
+}
+
+// SellerSelectors are selectors of seller profile pages. Ads of the seller are parsed
+// with ListingsSelectors.
+type SellerSelectors struct {
+	IDPattern string `yaml:"id_pattern"`
+	NameParam string `yaml:"name_param"`
+	Positive  string `yaml:"positive"`
+	Negative  string `yaml:"negative"`
+}
+
+// compile validates the selectors and compiles their patterns for site.
+func (s *Selectors) compile(site *Site) error {
+	required := map[string]string{
+		"breadcrumbs":           s.Breadcrumbs,
+		"date_layout":           s.DateLayout,
+		"date_pattern":          s.DatePattern,
+		"postcode_pattern":      s.PostCodePattern,
+		"views_pattern":         s.ViewsPattern,
+		"price_pattern":         s.PricePattern,
+		"listings.info":         s.Listings.Info,
+		"listings.ad":           s.Listings.Ad,
+		"listings.ad_link":      s.Listings.AdLink,
+		"listings.title":        s.Listings.Title,
+		"listings.date":         s.Listings.Date,
+		"listings.price":        s.Listings.Price,
+		"ad.title":              s.Ad.Title,
+		"ad.date":               s.Ad.Date,
+		"ad.description":        s.Ad.Description,
+		"ad.detail_rows":        s.Ad.DetailRows,
+		"listings.image_attr":   s.Listings.ImageAttr,
+		"ad.image_attr":         s.Ad.ImageAttr,
+		"listings.info_pattern": s.Listings.InfoPattern,
+		"ad.id_links":           s.Ad.IDLinks,
+		"ad.id_pattern":         s.Ad.IDPattern,
+		"phone.link":            s.Phone.Link,
+		"phone.pattern":         s.Phone.Pattern,
+		"catalog.links":         s.Catalog.Links,
+		"catalog.path_pattern":  s.Catalog.PathPattern,
+		"catalog.count_pattern": s.Catalog.CountPattern,
+		"seller.id_pattern":     s.Seller.IDPattern,
+	}
+	var missing []string
+	for name, v := range required {
+		if strings.TrimSpace(v) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	// the layout must round-trip at least day and month of a date
+	ref := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	if d, err := time.Parse(s.DateLayout, ref.Format(s.DateLayout)); err != nil || d.Day() != ref.Day() || d.Month() != ref.Month() {
+		return fmt.Errorf("invalid date_layout %q: day and month not parsed", s.DateLayout)
+	}
+	var err error
+	if s.infoRegexp, err = compileGroups("listings.info_pattern", s.Listings.InfoPattern, 3); err != nil {
+		return err
+	}
+	if s.idRegexp, err = compileGroups("ad.id_pattern", s.Ad.IDPattern, 1); err != nil {
+		return err
+	}
+	if s.dateRegexp, err = compileGroups("date_pattern", s.DatePattern, 0); err != nil {
+		return err
+	}
+	if s.sellerRegexp, err = compileGroups("seller.id_pattern", s.Seller.IDPattern, 1); err != nil {
+		return err
+	}
+	if s.postCodeRegexp, err = compileGroups("postcode_pattern", s.PostCodePattern, 0); err != nil {
+		return err
+	}
+	if s.viewsRegexp, err = compileGroups("views_pattern", s.ViewsPattern, 0); err != nil {
+		return err
+	}
+	if !strings.Contains(s.PricePattern, "{currency}") {
+		return fmt.Errorf("invalid price_pattern %q: missing {currency}", s.PricePattern)
+	}
+	currencies := []string{regexp.QuoteMeta(site.CurrencySymbol), regexp.QuoteMeta(site.Currency)}
+	for _, c := range site.CurrencyAliases {
+		currencies = append(currencies, regexp.QuoteMeta(c))
+	}
+	pricePattern := strings.ReplaceAll(s.PricePattern, "{currency}", strings.Join(currencies, "|"))
+	if s.priceRegexp, err = compileGroups("price_pattern", pricePattern, 1); err != nil {
+		return err
+	}
+	if s.phoneRegexp, err = compileGroups("phone.pattern", s.Phone.Pattern, 0); err != nil {
+		return err
+	}
+	if s.categoryPathRegexp, err = compileGroups("catalog.path_pattern", s.Catalog.PathPattern, 1); err != nil {
+		return err
+	}
+	if s.categoryCountRegexp, err = compileGroups("catalog.count_pattern", s.Catalog.CountPattern, 1); err != nil {
+		return err
+	}
+	return nil
+}
+
+func compileGroups(name, pattern string, groups int) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	if re.NumSubexp() != groups {
+		return nil, fmt.Errorf("invalid %s %q: expected %d groups, got %d", name, pattern, groups, re.NumSubexp())
+	}
+	return re, nil
+}
+
+// SelectorProfile holds selectors of all sites, the embedded defaults optionally
+// overridden by selector files.
+type SelectorProfile struct {
+	sites map[string]*Selectors
+}
+
+// selectorsFile is the format of selector files.
+type selectorsFile struct {
+	Version int                  `yaml:"version"`
+	Default yaml.Node            `yaml:"default"`
+	Sites   map[string]yaml.Node `yaml:"sites"`
+}
+
+var defaultSelectorProfile = mustSelectorProfile(newSelectorProfile())
+
+func mustSelectorProfile(p *SelectorProfile, err error) *SelectorProfile {
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded selectors: %v", err))
+	}
+	return p
+}
+
+// DefaultSelectorProfile returns the profile with the selectors embedded in the package.
+func DefaultSelectorProfile() *SelectorProfile {
+	return defaultSelectorProfile
+}
+
+// LoadSelectorProfile returns the embedded selectors overridden by selector file at path.
+// The file has the format of the embedded selectors.yaml and may set any subset of its values.
+func LoadSelectorProfile(path string) (*SelectorProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newSelectorProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("selectors %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// newSelectorProfile returns profile of the embedded selectors overridden by overrides in order.
+func newSelectorProfile(overrides ...[]byte) (*SelectorProfile, error) {
+	var files []selectorsFile
+	for i, data := range append([][]byte{defaultSelectorsYAML}, overrides...) {
+		var f selectorsFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, err
+		}
+		if i > 0 && f.Version != SelectorsVersion {
+			return nil, fmt.Errorf("unsupported version %d, expected %d", f.Version, SelectorsVersion)
+		}
+		for code := range f.Sites {
+			if _, ok := sites[code]; !ok {
+				return nil, fmt.Errorf("unknown site %q", code)
+			}
+		}
+		files = append(files, f)
+	}
+
+	p := &SelectorProfile{sites: make(map[string]*Selectors)}
+	for code, site := range sites {
+		// later values override earlier ones, fields missing in a file are kept
+		s := &Selectors{}
+		for _, f := range files {
+			if !f.Default.IsZero() {
+				if err := f.Default.Decode(s); err != nil {
+					return nil, err
+				}
+			}
+			if node, ok := f.Sites[code]; ok && !node.IsZero() {
+				if err := node.Decode(s); err != nil {
+					return nil, fmt.Errorf("site %s: %w", code, err)
+				}
+			}
+		}
+		if err := s.compile(site); err != nil {
+			return nil, fmt.Errorf("site %s: %w", code, err)
+		}
+		p.sites[code] = s
+	}
+	return p, nil
+}
+
+// ForSite returns selectors of site.
+func (p *SelectorProfile) ForSite(site *Site) *Selectors {
+	if s, ok := p.sites[site.Code]; ok {
+		return s
+	}
+	return defaultSelectorProfile.sites[DefaultSite.Code]
+}
+
+// Selectors returns selectors used by the client for its site.
+func (c *Client) Selectors() *Selectors {
+	return c.selectorsFor(c.site)
+}
+
+// SelectorProfile returns the selector profile used by the client for all sites.
+func (c *Client) SelectorProfile() *SelectorProfile {
+	return c.selectorProfile
+}
+
+// selectorsFor returns selectors of site used by the client.
+func (c *Client) selectorsFor(site *Site) *Selectors {
+	return c.selectorProfile.ForSite(site)
+}
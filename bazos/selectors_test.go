@@ -0,0 +1,104 @@
+package bazos
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultSelectorProfile(t *testing.T) {
+	for code, site := range sites {
+		sel := DefaultSelectorProfile().ForSite(site)
+		if sel.Ad.Description != ".popisdetail" || sel.infoRegexp == nil || sel.idRegexp == nil {
+			t.Errorf("site %s: unexpected selectors %+v", code, sel)
+		}
+	}
+}
+
+func TestSelectorPatternsPerSite(t *testing.T) {
+	tests := []struct {
+		site     *Site
+		postCode string
+		other    string
+		price    string
+	}{
+		{SiteSK, "811 01", "00-950", "Cena 1 250 eur"},
+		{SiteCZ, "11000", "1010", "Cena 1 250 Kč"},
+		{SitePL, "00-950", "811 01", "Cena 1 250 zł"},
+		{SiteAT, "1010", "11000", "Preis 1.250 €"},
+	}
+	for _, test := range tests {
+		sel := DefaultSelectorProfile().ForSite(test.site)
+		if !isPostCode(test.postCode, sel) || isPostCode(test.other, sel) {
+			t.Errorf("site %s: unexpected postcode pattern %q", test.site.Code, sel.PostCodePattern)
+		}
+		if amount, ok := extractPrice(test.price, sel); !ok || amount != 1250 {
+			t.Errorf("site %s: expected price 1250 in %q, got %v", test.site.Code, test.price, amount)
+		}
+	}
+}
+
+func TestLoadSelectorProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selectors.yaml")
+	data := `version: 1
+default:
+  ad:
+    description: ".popis-detail"
+sites:
+  cz:
+    listings:
+      price: ".cena b"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadSelectorProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sk, cz := p.ForSite(SiteSK), p.ForSite(SiteCZ)
+	if sk.Ad.Description != ".popis-detail" || cz.Ad.Description != ".popis-detail" {
+		t.Errorf("expected overridden description, got %q and %q", sk.Ad.Description, cz.Ad.Description)
+	}
+	if sk.Listings.Price != ".inzeratycena b" || cz.Listings.Price != ".cena b" {
+		t.Errorf("expected price overridden only for cz, got %q and %q", sk.Listings.Price, cz.Listings.Price)
+	}
+	if sk.Ad.Title != ".inzeratydetnadpis H1" || sk.DateLayout != "2.1. 2006" {
+		t.Errorf("expected values missing in the file to be kept, got %+v", sk)
+	}
+
+	// the renamed selector no longer matches the fixture
+	ad, err := parseAdListing(openFixture(t, "ad_detail.html"), SiteSK, sk, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.Description != "" {
+		t.Errorf("expected no description, got %q", ad.Description)
+	}
+}
+
+func TestLoadSelectorProfileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"version", "version: 2\n", "unsupported version"},
+		{"site", "version: 1\nsites:\n  xx:\n    breadcrumbs: a\n", `unknown site "xx"`},
+		{"empty selector", "version: 1\ndefault:\n  listings:\n    ad: \"\"\n", "missing listings.ad"},
+		{"pattern groups", "version: 1\ndefault:\n  ad:\n    id_pattern: 'idi=\\d+'\n", "expected 1 groups, got 0"},
+		{"pattern", "version: 1\ndefault:\n  listings:\n    info_pattern: '(\\d+'\n", "invalid listings.info_pattern"},
+		{"date layout", "version: 1\ndefault:\n  date_layout: \"x\"\n", "invalid date_layout"},
+		{"price pattern", "version: 1\ndefault:\n  price_pattern: '(\\d+) EUR'\n", "missing {currency}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newSelectorProfile([]byte(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
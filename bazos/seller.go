@@ -0,0 +1,146 @@
+package bazos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+)
+
+// Seller is a seller profile with active ads of the seller.
+type Seller struct {
+	// ID is the seller ID, see Ad.UserID.
+	ID   string
+	Name string
+	// Link is the URL of the seller profile page.
+	Link string
+	// PositiveRatings and NegativeRatings are the counts of ratings by buyers.
+	PositiveRatings int
+	NegativeRatings int
+	// Total is the number of active ads stated by the profile page.
+	Total int
+	// Ads are the fetched active ads, fewer than Total if limited by ListOptions.
+	Ads []Ad
+	// Truncated reports whether not all ads were fetched.
+	Truncated bool `json:",omitempty" yaml:",omitempty"`
+	// Diagnostics describes problems found while parsing the profile page.
+	Diagnostics Diagnostics
+}
+
+// GetSeller fetches seller profile page at u, e.g. Ad.UserLink, with active ads of the seller
+// from the profile listings and its next pages limited by opts.
+func (c *Client) GetSeller(ctx context.Context, u string, opts ListOptions) (*Seller, error) {
+	logrus.Debugf("fetching seller from url: %v", u)
+
+	body, pageUrl, err := c.fetch(ctx, u, pageListing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch seller url: %w", err)
+	}
+	site := c.siteForURL(pageUrl)
+	sel := c.selectorsFor(site)
+	if err := checkBlocked(pageUrl, body, sel.Listings.Info); err != nil {
+		return nil, err
+	}
+
+	seller, err := parseSeller(bytes.NewReader(body), pageUrl, sel)
+	if err != nil {
+		return nil, err
+	}
+	page, err := c.parseAdListingsPage(pageUrl, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seller ads: %w", err)
+	}
+	seller.Total = page.Total
+	seller.Diagnostics.Missing = append(seller.Diagnostics.Missing, page.Diagnostics.Missing...)
+	seller.Diagnostics.ParseErrors = append(seller.Diagnostics.ParseErrors, page.Diagnostics.ParseErrors...)
+
+	// the first page is already fetched, the iterator continues with the next ones
+	it := c.IterListings(ctx, pageUrl, opts)
+	it.setPage(page)
+	for it.Next() {
+		seller.Ads = append(seller.Ads, it.Ad())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	seller.Truncated = it.Truncated()
+	for i := range seller.Ads {
+		ad := &seller.Ads[i]
+		ad.UserName, ad.UserLink, ad.UserID = seller.Name, seller.Link, seller.ID
+	}
+	return seller, nil
+}
+
+// GetAdSeller fetches seller of the ad, see GetSeller.
+func (c *Client) GetAdSeller(ctx context.Context, ad *Ad, opts ListOptions) (*Seller, error) {
+	if ad.UserLink == "" {
+		return nil, fmt.Errorf("ad %s has no seller link", ad.ID)
+	}
+	seller, err := c.GetSeller(ctx, ad.UserLink, opts)
+	if err != nil {
+		return nil, err
+	}
+	if seller.Name == "" {
+		seller.Name = ad.UserName
+		for i := range seller.Ads {
+			seller.Ads[i].UserName = seller.Name
+		}
+	}
+	return seller, nil
+}
+
+// parseSeller parses seller profile from page at u, the ads are parsed separately.
+func parseSeller(r io.Reader, u string, sel *Selectors) (*Seller, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	seller := &Seller{Link: u}
+	if m := sel.sellerRegexp.FindStringSubmatch(u); m != nil {
+		seller.ID = m[1]
+	} else {
+		seller.Diagnostics.ParseErrors = append(seller.Diagnostics.ParseErrors, ParseError{
+			Field:  "ID",
+			Value:  u,
+			Reason: "seller ID not found in URL",
+		})
+	}
+	if pu, err := url.Parse(u); err == nil && sel.Seller.NameParam != "" {
+		seller.Name = strings.TrimSpace(pu.Query().Get(sel.Seller.NameParam))
+	}
+
+	ratings := []struct {
+		field    string
+		selector string
+		count    *int
+	}{
+		{"PositiveRatings", sel.Seller.Positive, &seller.PositiveRatings},
+		{"NegativeRatings", sel.Seller.Negative, &seller.NegativeRatings},
+	}
+	for _, rating := range ratings {
+		if rating.selector == "" {
+			continue
+		}
+		s := doc.Find(rating.selector).First()
+		if s.Length() == 0 {
+			seller.Diagnostics.addMissing(rating.field)
+			continue
+		}
+		text := strings.TrimSpace(s.Text())
+		if *rating.count, err = strconv.Atoi(strings.Join(strings.Fields(text), "")); err != nil {
+			seller.Diagnostics.ParseErrors = append(seller.Diagnostics.ParseErrors, ParseError{
+				Field:  rating.field,
+				Value:  text,
+				Reason: err.Error(),
+			})
+		}
+	}
+	return seller, nil
+}
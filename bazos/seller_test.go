@@ -0,0 +1,70 @@
+package bazos
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClientGetSeller(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/hodnotenie.php", "seller_peter.html")
+
+	u := "http://www." + testDomain + "/hodnotenie.php?idmail=1234567&jmeno=Peter"
+	seller, err := client.GetSeller(context.Background(), u, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seller.ID != "1234567" || seller.Name != "Peter" || seller.Link != u {
+		t.Errorf("unexpected seller %q (%s) at %s", seller.Name, seller.ID, seller.Link)
+	}
+	if seller.PositiveRatings != 12 || seller.NegativeRatings != 1 {
+		t.Errorf("expected ratings 12/1, got %d/%d", seller.PositiveRatings, seller.NegativeRatings)
+	}
+	if seller.Total != 2 || len(seller.Ads) != 2 || seller.Truncated {
+		t.Fatalf("expected all 2 ads, got %d of %d", len(seller.Ads), seller.Total)
+	}
+	for _, ad := range seller.Ads {
+		if ad.UserID != seller.ID || ad.UserName != "Peter" {
+			t.Errorf("ad %s: expected seller of the profile, got %q (%s)", ad.ID, ad.UserName, ad.UserID)
+		}
+	}
+	if !seller.Diagnostics.OK() {
+		t.Errorf("unexpected diagnostics %+v", seller.Diagnostics)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+
+	seller, err = client.GetSeller(context.Background(), u, ListOptions{MaxResults: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seller.Ads) != 1 || !seller.Truncated {
+		t.Errorf("expected 1 ad and truncated seller, got %d ads", len(seller.Ads))
+	}
+}
+
+func TestClientGetAdSeller(t *testing.T) {
+	srv, client := newTestServer(t)
+	srv.Handle("/hodnotenie.php", "seller_peter.html")
+
+	ad, err := parseAdListing(openFixture(t, "ad_detail.html"), SiteSK, defaultSelectors(SiteSK), testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.UserID != "1234567" {
+		t.Errorf("expected seller ID 1234567, got %q", ad.UserID)
+	}
+	ad.UserLink = "http://www." + testDomain + "/hodnotenie.php?idmail=1234567"
+	seller, err := client.GetAdSeller(context.Background(), ad, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seller.Name != ad.UserName || seller.Ads[0].UserName != ad.UserName {
+		t.Errorf("expected seller name %q from ad, got %q", ad.UserName, seller.Name)
+	}
+
+	if _, err := client.GetAdSeller(context.Background(), &Ad{ID: "1"}, ListOptions{}); err == nil {
+		t.Error("expected error for ad without seller link")
+	}
+}
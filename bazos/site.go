@@ -0,0 +1,258 @@
+package bazos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Site is a country-specific bazos site profile.
+type Site struct {
+	// Code is the country code of the site, e.g. "sk".
+	Code string
+	// Domain is the base domain of the site, e.g. "bazos.sk".
+	Domain string
+	// AdPath is the path segment of ad detail URLs followed by the ad ID, e.g. "inzerat".
+	AdPath string
+	// Currency is the ISO 4217 code of the currency used for prices.
+	Currency string
+	// CurrencySymbol is the symbol displayed after prices.
+	CurrencySymbol string
+	// CurrencyAliases are other spellings of the currency used in ad descriptions.
+	CurrencyAliases []string
+
+	// Sections lists the section (rubrika) slugs.
+	Sections []string
+	// SectionNames maps section slugs to their display names.
+	SectionNames map[string]string
+
+	// NextPage is the text of the link to the next page of listings.
+	NextPage string
+	// NoResults is the text displayed when search matches no ads.
+	NoResults string
+	// PriceFree is the price text of ads given away for free.
+	PriceFree string
+	// PriceInText is the price text of ads with price stated in description.
+	PriceInText string
+	// PriceNegotiable are the price texts of ads with negotiable price.
+	PriceNegotiable []string
+
+	// DetailLabels are the row labels of the ad detail table.
+	DetailLabels DetailLabels
+	// PostCodeDigits is the number of digits of postcodes.
+	PostCodeDigits int
+	// TimeZone is the IANA time zone of ad dates.
+	TimeZone string
+	// RelativeDays maps lowercase words of relative dates, e.g. "dnes", to days before today.
+	RelativeDays map[string]int
+
+	locOnce sync.Once
+	loc     *time.Location
+}
+
+// DetailLabels are the labels (without trailing colon) of the rows in ad detail table.
+type DetailLabels struct {
+	Name     string
+	Phone    string
+	Location string
+	Views    string
+	Price    string
+}
+
+var (
+	SiteSK = &Site{
+		Code:            "sk",
+		Domain:          "bazos.sk",
+		AdPath:          "inzerat",
+		Currency:        "EUR",
+		CurrencySymbol:  "€",
+		Sections:        sectionsList,
+		SectionNames:    sectionsNames,
+		NextPage:        "Ďalšia",
+		NoResults:       "Hľadaniu nevyhovujú žiadne inzeráty",
+		PriceFree:       PriceFree,
+		PriceInText:     PriceInText,
+		PriceNegotiable: []string{"Dohodou", "Dohoda", "Ponúknite"},
+		CurrencyAliases: []string{"eur", "euro", "eura"},
+		PostCodeDigits:  5,
+		TimeZone:        "Europe/Bratislava",
+		RelativeDays:    map[string]int{"dnes": 0, "včera": 1},
+		DetailLabels: DetailLabels{
+			Name:     "Meno",
+			Phone:    "Telefón",
+			Location: "Lokalita",
+			Views:    "Videné",
+			Price:    "Cena",
+		},
+	}
+	SiteCZ = &Site{
+		Code:            "cz",
+		Domain:          "bazos.cz",
+		AdPath:          "inzerat",
+		Currency:        "CZK",
+		CurrencySymbol:  "Kč",
+		Sections:        sectionsListCZ,
+		SectionNames:    sectionsNamesCZ,
+		NextPage:        "Další",
+		NoResults:       "Hledání nevyhovují žádné inzeráty",
+		PriceFree:       "Zdarma",
+		PriceInText:     "V textu",
+		PriceNegotiable: []string{"Dohodou", "Nabídněte"},
+		CurrencyAliases: []string{"kc", ",-"},
+		PostCodeDigits:  5,
+		TimeZone:        "Europe/Prague",
+		RelativeDays:    map[string]int{"dnes": 0, "včera": 1},
+		DetailLabels: DetailLabels{
+			Name:     "Jméno",
+			Phone:    "Telefon",
+			Location: "Lokalita",
+			Views:    "Vidělo",
+			Price:    "Cena",
+		},
+	}
+	SitePL = &Site{
+		Code:            "pl",
+		Domain:          "bazos.pl",
+		AdPath:          "ogloszenie",
+		Currency:        "PLN",
+		CurrencySymbol:  "zł",
+		Sections:        sectionsListPL,
+		SectionNames:    sectionsNamesPL,
+		NextPage:        "Następna",
+		NoResults:       "Wyszukiwaniu nie odpowiadają żadne ogłoszenia",
+		PriceFree:       "Za darmo",
+		PriceInText:     "W tekście",
+		PriceNegotiable: []string{"Do negocjacji", "Zaproponuj"},
+		CurrencyAliases: []string{"zl", "złotych"},
+		PostCodeDigits:  5,
+		TimeZone:        "Europe/Warsaw",
+		RelativeDays:    map[string]int{"dzisiaj": 0, "dziś": 0, "wczoraj": 1},
+		DetailLabels: DetailLabels{
+			Name:     "Imię",
+			Phone:    "Telefon",
+			Location: "Lokalizacja",
+			Views:    "Wyświetlenia",
+			Price:    "Cena",
+		},
+	}
+	SiteAT = &Site{
+		Code:            "at",
+		Domain:          "bazos.at",
+		AdPath:          "anzeige",
+		Currency:        "EUR",
+		CurrencySymbol:  "€",
+		Sections:        sectionsListAT,
+		SectionNames:    sectionsNamesAT,
+		NextPage:        "Weiter",
+		NoResults:       "Der Suche entsprechen keine Anzeigen",
+		PriceFree:       "Gratis",
+		PriceInText:     "Im Text",
+		PriceNegotiable: []string{"VB", "Verhandlungsbasis"},
+		CurrencyAliases: []string{"eur", "euro"},
+		PostCodeDigits:  4,
+		TimeZone:        "Europe/Vienna",
+		RelativeDays:    map[string]int{"heute": 0, "gestern": 1},
+		DetailLabels: DetailLabels{
+			Name:     "Name",
+			Phone:    "Telefon",
+			Location: "Ort",
+			Views:    "Gesehen",
+			Price:    "Preis",
+		},
+	}
+)
+
+// DefaultSite is the site used by default.
+var DefaultSite = SiteSK
+
+var sites = map[string]*Site{
+	SiteSK.Code: SiteSK,
+	SiteCZ.Code: SiteCZ,
+	SitePL.Code: SitePL,
+	SiteAT.Code: SiteAT,
+}
+
+// Sites returns all known sites sorted by code.
+func Sites() []*Site {
+	list := make([]*Site, 0, len(sites))
+	for _, s := range sites {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+	return list
+}
+
+// LookupSite returns site for the country code or domain.
+func LookupSite(code string) (*Site, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if s, ok := sites[code]; ok {
+		return s, nil
+	}
+	for _, s := range sites {
+		if code == s.Domain {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown site %q", code)
+}
+
+// siteForHost returns site which domain matches host.
+func siteForHost(host string) (*Site, bool) {
+	for _, s := range sites {
+		if host == s.Domain || strings.HasSuffix(host, "."+s.Domain) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// SectionName returns display name of the section slug.
+func (s *Site) SectionName(section string) string {
+	if n, ok := s.SectionNames[section]; ok {
+		return n
+	}
+	return section
+}
+
+// HasSection reports whether section slug exists on the site.
+func (s *Site) HasSection(section string) bool {
+	for _, sec := range s.Sections {
+		if sec == section {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePostCode validates postcode and returns it in the format used in search queries.
+func (s *Site) NormalizePostCode(postCode string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\u00a0' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(postCode))
+	valid := len(digits) == s.PostCodeDigits
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			valid = false
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("invalid postcode %q, expected %d digits", postCode, s.PostCodeDigits)
+	}
+	return digits, nil
+}
+
+func (s *Site) String() string {
+	return s.Domain
+}
+
+// matchesText reports whether text equals marker, ignoring spaces.
+func matchesText(text, marker string) bool {
+	return marker != "" && strings.ReplaceAll(text, " ", "") == strings.ReplaceAll(marker, " ", "")
+}
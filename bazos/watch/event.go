@@ -0,0 +1,87 @@
+package watch
+
+import (
+	"fmt"
+	"time"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+// EventType is the type of change detected for an ad.
+type EventType string
+
+const (
+	EventNew           EventType = "new"
+	EventPriceDrop     EventType = "price-drop"
+	EventPriceIncrease EventType = "price-increase"
+	EventEdited        EventType = "edited"
+	EventBumped        EventType = "bumped"
+	EventRemoved       EventType = "removed"
+)
+
+// Event is a change of an ad detected by the watcher.
+type Event struct {
+	Type  EventType
+	Watch string
+	Time  time.Time
+	Ad    bazos.Ad
+	// Previous is the previously seen version of the ad, nil for new ads.
+	Previous *bazos.Ad `json:",omitempty" yaml:",omitempty"`
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case EventPriceDrop, EventPriceIncrease:
+		return fmt.Sprintf("[%s] %s: %v -> %v (%s)", e.Type, e.Ad.Title, e.Previous.Price, e.Ad.Price, e.Ad.Link)
+	default:
+		return fmt.Sprintf("[%s] %s: %v (%s)", e.Type, e.Ad.Title, e.Ad.Price, e.Ad.Link)
+	}
+}
+
+// Diff compares seen ads with current ads and returns detected events.
+// Ads missing from current are reported as removed only if complete is true,
+// i.e. current contains all ads matching the query.
+func Diff(seen map[string]SeenAd, current []bazos.Ad, complete bool) []Event {
+	var events []Event
+	found := make(map[string]bool, len(current))
+
+	for _, ad := range current {
+		if ad.ID == "" {
+			continue
+		}
+		found[ad.ID] = true
+
+		prev, ok := seen[ad.ID]
+		if !ok {
+			events = append(events, Event{Type: EventNew, Ad: ad})
+			continue
+		}
+		p := prev.Ad
+		if oldPrice, ok := p.Price.Value(); ok {
+			if newPrice, ok := ad.Price.Value(); ok {
+				if newPrice < oldPrice {
+					events = append(events, Event{Type: EventPriceDrop, Ad: ad, Previous: &p})
+				} else if newPrice > oldPrice {
+					events = append(events, Event{Type: EventPriceIncrease, Ad: ad, Previous: &p})
+				}
+			}
+		}
+		if ad.Title != p.Title || ad.Description != p.Description {
+			events = append(events, Event{Type: EventEdited, Ad: ad, Previous: &p})
+		}
+		if (ad.Top && !p.Top) || ad.Listed().After(p.Listed()) {
+			events = append(events, Event{Type: EventBumped, Ad: ad, Previous: &p})
+		}
+	}
+
+	if complete {
+		for id, prev := range seen {
+			if !found[id] {
+				p := prev.Ad
+				events = append(events, Event{Type: EventRemoved, Ad: p, Previous: &p})
+			}
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,78 @@
+package watch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/internal/fsutil"
+)
+
+// SeenAd is an ad stored by the watcher.
+type SeenAd struct {
+	Ad        bazos.Ad
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Store persists seen ads of watches in a directory, one JSON file per watch.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a Store in dir, creating the directory if needed.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// DefaultStoreDir returns the default directory of the store.
+func DefaultStoreDir() string {
+	return fsutil.CacheDir("watch")
+}
+
+// path returns file of the watch. The file name is the watch name made safe for file
+// systems with a hash of the name, as names differing only in replaced characters would
+// share the file otherwise.
+func (s *Store) path(watch string) string {
+	sum := sha256.Sum256([]byte(watch))
+	return filepath.Join(s.dir, fsutil.SafeName(watch)+"-"+hex.EncodeToString(sum[:4])+".json")
+}
+
+// Has reports whether seen ads of the watch were saved before.
+func (s *Store) Has(watch string) bool {
+	_, err := os.Stat(s.path(watch))
+	return err == nil
+}
+
+// Load returns seen ads of the watch keyed by ad ID.
+func (s *Store) Load(watch string) (map[string]SeenAd, error) {
+	seen := make(map[string]SeenAd)
+	data, err := os.ReadFile(s.path(watch))
+	if errors.Is(err, os.ErrNotExist) {
+		return seen, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &seen); err != nil {
+		return nil, fmt.Errorf("failed to decode store of watch %q: %w", watch, err)
+	}
+	return seen, nil
+}
+
+// Save stores seen ads of the watch.
+func (s *Store) Save(watch string, seen map[string]SeenAd) error {
+	data, err := json.MarshalIndent(seen, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(s.path(watch), data, 0o644)
+}
@@ -0,0 +1,175 @@
+// Package watch periodically runs saved searches and reports changes of the ads.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/history"
+)
+
+// DefaultInterval is the interval of watches without interval set.
+const DefaultInterval = 15 * time.Minute
+
+// Watch is a saved search checked periodically.
+type Watch struct {
+	Name     string
+	Query    bazos.SearchQuery
+	Interval time.Duration `json:",omitempty" yaml:",omitempty"`
+}
+
+// Config is a list of watches, usually loaded from YAML file.
+type Config struct {
+	Watches []Watch
+}
+
+// LoadConfig loads watches config from YAML file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse watch config: %w", err)
+	}
+	for i, w := range cfg.Watches {
+		if w.Name == "" {
+			return nil, fmt.Errorf("watch #%d has no name", i+1)
+		}
+	}
+	return &cfg, nil
+}
+
+// Watcher checks watches and reports changes of their ads.
+type Watcher struct {
+	Client *bazos.Client
+	Store  *Store
+	// ListOptions limits the number of pages fetched for each check.
+	ListOptions bazos.ListOptions
+	// History records observations of checked ads, if set.
+	History *history.Store
+	// NotifyExisting reports ads found by the first check of a watch as new. By default
+	// the first check only stores them, so that existing ads are not reported.
+	NotifyExisting bool
+}
+
+// Check runs the watch query once, updates the store and returns detected events.
+func (w *Watcher) Check(ctx context.Context, watch Watch) ([]Event, error) {
+	log := logrus.WithField("watch", watch.Name)
+
+	first := !w.Store.Has(watch.Name)
+	seen, err := w.Store.Load(watch.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	it := w.Client.IterAds(ctx, watch.Query, w.ListOptions)
+	ads, err := it.All()
+	if err != nil {
+		return nil, fmt.Errorf("searching watch %q failed: %w", watch.Name, err)
+	}
+	complete := !it.Truncated()
+	if !complete {
+		log.Warnf("results truncated after %d pages, removed ads will not be detected", it.Pages())
+	}
+	log.Debugf("checked %d ads (%d seen before)", len(ads), len(seen))
+
+	now := time.Now()
+	if w.History != nil {
+		if err := w.History.Record(history.SeriesName(watch.Query), ads, now); err != nil {
+			log.Warnf("recording history failed: %v", err)
+		}
+	}
+
+	// reposts collapsed into their originals are still listed, they are not removed
+	reposts := make(map[string]bool)
+	for _, ad := range it.Reposts() {
+		reposts[ad.ID] = true
+	}
+	var events []Event
+	if first && !w.NotifyExisting {
+		log.Infof("first check, storing %d existing ads without events", len(ads))
+	} else {
+		for _, e := range Diff(seen, ads, complete) {
+			if e.Type == EventRemoved && reposts[e.Ad.ID] {
+				continue
+			}
+			e.Watch = watch.Name
+			e.Time = now
+			events = append(events, e)
+		}
+	}
+
+	for _, ad := range ads {
+		if ad.ID == "" {
+			continue
+		}
+		s, ok := seen[ad.ID]
+		if !ok {
+			s.FirstSeen = now
+		}
+		s.Ad = ad
+		s.LastSeen = now
+		seen[ad.ID] = s
+	}
+	for _, e := range events {
+		if e.Type == EventRemoved {
+			delete(seen, e.Ad.ID)
+		}
+	}
+
+	if err := w.Store.Save(watch.Name, seen); err != nil {
+		return nil, fmt.Errorf("saving watch %q failed: %w", watch.Name, err)
+	}
+	return events, nil
+}
+
+// Run checks all watches in their intervals until ctx is done, calling handle for each event.
+// Errors of individual checks are logged and the watch is retried in the next interval.
+func (w *Watcher) Run(ctx context.Context, watches []Watch, handle func(Event)) error {
+	if len(watches) == 0 {
+		return fmt.Errorf("no watches to run")
+	}
+	next := make([]time.Time, len(watches))
+	for {
+		now := time.Now()
+		wake := now.Add(DefaultInterval)
+		for i, watch := range watches {
+			if !next[i].After(now) {
+				events, err := w.Check(ctx, watch)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					logrus.Errorf("checking watch %q failed: %v", watch.Name, err)
+				}
+				for _, e := range events {
+					handle(e)
+				}
+				interval := watch.Interval
+				if interval <= 0 {
+					interval = DefaultInterval
+				}
+				next[i] = time.Now().Add(interval)
+			}
+			if next[i].Before(wake) {
+				wake = next[i]
+			}
+		}
+
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/internal/bazostest"
+)
+
+func newTestWatcher(t *testing.T) (*bazostest.Server, *Watcher) {
+	t.Helper()
+	srv := bazostest.NewServer(t, "..")
+	client := bazos.NewClient(
+		bazos.WithHTTPClient(srv.HTTPClient()),
+		bazos.WithScheme("http"),
+		bazos.WithDomain("bazos.test"),
+		bazos.WithCrawlPolicy(bazos.CrawlPolicy{}),
+	)
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, &Watcher{Client: client, Store: store}
+}
+
+func eventSummary(events []Event) string {
+	var list []string
+	for _, e := range events {
+		list = append(list, string(e.Type)+":"+e.Ad.ID)
+	}
+	sort.Strings(list)
+	return strings.Join(list, " ")
+}
+
+func TestWatcherCheck(t *testing.T) {
+	srv, watcher := newTestWatcher(t)
+	srv.Handle("/search.php", "testdata/search_electrolux.html")
+	srv.Handle("/search.php?hledat=electrolux&rubriky=elektro&crz=3", "testdata/search_electrolux_3.html")
+
+	watch := Watch{Name: "electrolux", Query: bazos.SearchQuery{Query: "electrolux"}}
+
+	watcher.NotifyExisting = true
+	events, err := watcher.Check(context.Background(), watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "new:150777777 new:150888888 new:150999999 new:151111111 new:151234567"
+	if got := eventSummary(events); got != want {
+		t.Errorf("first check:\n got: %s\nwant: %s", got, want)
+	}
+	for _, e := range events {
+		if e.Watch != "electrolux" || e.Time.IsZero() {
+			t.Errorf("event not annotated: %+v", e)
+		}
+	}
+
+	srv.Handle("/search.php", "watch/testdata/search_changed.html")
+
+	events, err = watcher.Check(context.Background(), watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "bumped:150888888 edited:150999999 new:152000000 price-drop:151234567 price-increase:150888888 removed:151111111"
+	if got := eventSummary(events); got != want {
+		t.Errorf("second check:\n got: %s\nwant: %s", got, want)
+	}
+
+	seen, err := watcher.Store.Load(watch.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected 5 stored ads, got %d", len(seen))
+	}
+	if _, ok := seen["151111111"]; ok {
+		t.Error("expected removed ad to be deleted from store")
+	}
+	if s := seen["151234567"]; s.FirstSeen.IsZero() || s.FirstSeen.After(s.LastSeen) {
+		t.Errorf("expected first seen before last seen: %v, %v", s.FirstSeen, s.LastSeen)
+	}
+
+	events, err = watcher.Check(context.Background(), watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for unchanged results, got: %s", eventSummary(events))
+	}
+}
+
+func TestWatcherCheckFirstRun(t *testing.T) {
+	srv, watcher := newTestWatcher(t)
+	srv.Handle("/search.php", "testdata/search_electrolux.html")
+	srv.Handle("/search.php?hledat=electrolux&rubriky=elektro&crz=3", "testdata/search_electrolux_3.html")
+	watch := Watch{Name: "electrolux", Query: bazos.SearchQuery{Query: "electrolux"}}
+
+	events, err := watcher.Check(context.Background(), watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for existing ads, got: %s", eventSummary(events))
+	}
+	seen, err := watcher.Store.Load(watch.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected 5 stored ads, got %d", len(seen))
+	}
+
+	srv.Handle("/search.php", "watch/testdata/search_changed.html")
+	events, err = watcher.Check(context.Background(), watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventSummary(events); !strings.Contains(got, "new:152000000") {
+		t.Errorf("expected new ad after first check, got: %s", got)
+	}
+}
+
+func TestStoreNames(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both names are made safe to the same file name
+	a := map[string]SeenAd{"1": {}}
+	b := map[string]SeenAd{"2": {}, "3": {}}
+	if err := store.Save("pračka/sk", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("pračka:sk", b); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has("pračka sk") {
+		t.Error("expected unsaved watch to be missing")
+	}
+	for name, want := range map[string]int{"pračka/sk": 1, "pračka:sk": 2} {
+		seen, err := store.Load(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !store.Has(name) || len(seen) != want {
+			t.Errorf("%s: expected %d stored ads, got %d", name, want, len(seen))
+		}
+	}
+}
+
+func TestWatcherCheckCollapsed(t *testing.T) {
+	srv, watcher := newTestWatcher(t)
+	srv.Handle("/search.php", "testdata/search_reposts.html")
+	watch := Watch{Name: "bicykel", Query: bazos.SearchQuery{Query: "bicykel"}}
+
+	if _, err := watcher.Check(context.Background(), watch); err != nil {
+		t.Fatal(err)
+	}
+
+	// the repost seen before is collapsed into its original, but it is still listed
+	watcher.ListOptions.CollapseReposts = true
+	events, err := watcher.Check(context.Background(), watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for collapsed repost, got: %s", eventSummary(events))
+	}
+	seen, err := watcher.Store.Load(watch.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := seen["153000000"]; !ok {
+		t.Error("expected collapsed repost to stay in store")
+	}
+}
+
+func TestDiffIncomplete(t *testing.T) {
+	seen := map[string]SeenAd{
+		"1": {Ad: bazos.Ad{ID: "1", Title: "a"}},
+		"2": {Ad: bazos.Ad{ID: "2", Title: "b"}},
+	}
+	current := []bazos.Ad{{ID: "1", Title: "a"}}
+
+	if events := Diff(seen, current, false); len(events) != 0 {
+		t.Errorf("expected no events for incomplete results, got: %s", eventSummary(events))
+	}
+	if got := eventSummary(Diff(seen, current, true)); got != "removed:2" {
+		t.Errorf("expected removed event, got: %s", got)
+	}
+}
+
+func TestDiffBumped(t *testing.T) {
+	posted := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	bumped := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	seen := map[string]SeenAd{
+		"1": {Ad: bazos.Ad{ID: "1", Title: "a", Date: posted}},
+		"2": {Ad: bazos.Ad{ID: "2", Title: "b", Date: posted, Bumped: &bumped}},
+	}
+	// the posted date stays, the later date of the listing moves
+	current := []bazos.Ad{
+		{ID: "1", Title: "a", Date: posted, Bumped: &bumped},
+		{ID: "2", Title: "b", Date: posted, Bumped: &bumped},
+	}
+	if got := eventSummary(Diff(seen, current, true)); got != "bumped:1" {
+		t.Errorf("expected bumped event, got: %s", got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watches.yaml")
+	const config = `
+watches:
+  - name: pracky
+    interval: 30m
+    query:
+      query: electrolux
+      section:
+        section: elektro
+        category: pracky
+      priceto: 300
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Watches) != 1 {
+		t.Fatalf("expected 1 watch, got %d", len(cfg.Watches))
+	}
+	w := cfg.Watches[0]
+	if w.Name != "pracky" || w.Interval != 30*time.Minute || w.Query.Query != "electrolux" ||
+		w.Query.Section == nil || w.Query.Section.Category != "pracky" || w.Query.PriceTo != 300 {
+		t.Errorf("unexpected watch: %+v", w)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var fixtures string
+	cmd := &cobra.Command{
+		Use:   "doctor [query]",
+		Short: "Checks that pages of the site are still parsed correctly",
+		Long: `Fetches reference pages, the first result page of the query (default '` + bazos.DefaultHealthQuery.Query + `')
+and detail of its first ad, and checks that every selector used by the parsers matches and every
+ad field parses. Exits with non-zero status when the site layout changed.
+
+With --fixtures the pages listed in the manifest ` + fixturesManifest + ` of the directory are checked
+instead of fetched pages, e.g. bazos/testdata. The manifest maps file names to the page type
+(listings or ad) and site code:
+
+  pages:
+    search_electrolux.html: {type: listings, site: sk}
+    ad_detail.html: {type: ad, site: sk}
+
+Use with --selectors to verify a selector file fixing a layout change.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				report *bazos.HealthReport
+				err    error
+			)
+			if fixtures != "" {
+				report, err = checkFixtures(fixtures, client.SelectorProfile())
+			} else {
+				q := bazos.DefaultHealthQuery
+				if len(args) > 0 {
+					q.Query = strings.Join(args, " ")
+				}
+				report, err = client.CheckHealth(cmd.Context(), q)
+			}
+			if err != nil {
+				return err
+			}
+			if err := printHealthReport(report); err != nil {
+				return err
+			}
+			if !report.OK() {
+				return fmt.Errorf("health check failed, the site layout may have changed")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&fixtures, "fixtures", "", "Directory of saved pages to check instead of fetching")
+	return cmd
+}
+
+// fixturesManifest is the file listing saved pages checked by checkFixtures.
+const fixturesManifest = "doctor.yaml"
+
+// fixturePage is the page type and site of a saved page.
+type fixturePage struct {
+	Type bazos.PageType `yaml:"type"`
+	Site string         `yaml:"site"`
+}
+
+// checkFixtures checks saved pages listed in the manifest of dir using selectors of profile.
+func checkFixtures(dir string, profile *bazos.SelectorProfile) (*bazos.HealthReport, error) {
+	data, err := os.ReadFile(filepath.Join(dir, fixturesManifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures manifest: %w", err)
+	}
+	var manifest struct {
+		Pages map[string]fixturePage `yaml:"pages"`
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures manifest: %w", err)
+	}
+	if len(manifest.Pages) == 0 {
+		return nil, fmt.Errorf("no pages in %s", filepath.Join(dir, fixturesManifest))
+	}
+	files := make([]string, 0, len(manifest.Pages))
+	for file := range manifest.Pages {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	sites := make(map[string]bool)
+	report := &bazos.HealthReport{Checked: time.Now()}
+	for _, file := range files {
+		page := manifest.Pages[file]
+		site, err := bazos.LookupSite(page.Site)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		path := filepath.Join(dir, file)
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		var check bazos.PageCheck
+		switch page.Type {
+		case bazos.PageTypeListings:
+			check = bazos.CheckListingsPage(f, site, profile.ForSite(site))
+		case bazos.PageTypeAd:
+			check = bazos.CheckAdPage(f, site, profile.ForSite(site))
+		default:
+			f.Close()
+			return nil, fmt.Errorf("%s: invalid page type %q, expected %s or %s", file, page.Type, bazos.PageTypeListings, bazos.PageTypeAd)
+		}
+		f.Close()
+		check.Source = path
+		report.Pages = append(report.Pages, check)
+		sites[site.Code] = true
+	}
+	// fixtures of several sites are reported as e.g. "cz,sk"
+	codes := make([]string, 0, len(sites))
+	for code := range sites {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	report.Site = strings.Join(codes, ",")
+	return report, nil
+}
+
+func printHealthReport(report *bazos.HealthReport) error {
+	format, _, _ := strings.Cut(output.Format, "=")
+	switch format {
+	case "json":
+		return encodeJSON(os.Stdout, report)
+	case "yaml":
+		return encodeYAML(os.Stdout, report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, page := range report.Pages {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s page %s: %s\n", page.Type, page.Source, healthStatus(page.OK(), true))
+		if page.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", page.Error)
+		}
+		for _, s := range page.Selectors {
+			fmt.Fprintf(w, "  selector\t%s\t%d\t%s\n", s.Selector, s.Matches, healthStatus(s.OK(), s.Required || s.Matches > 0))
+		}
+		for _, f := range page.Fields {
+			fmt.Fprintf(w, "  field\t%s\t%d/%d\t%s\n", f.Field, f.Parsed, f.Total, healthStatus(f.OK(), f.Required || f.Parsed == f.Total))
+		}
+		for _, e := range page.Diagnostics.ParseErrors {
+			fmt.Fprintf(w, "  parse error\t%s\t\t%s\n", e.Field, e.Reason)
+		}
+	}
+	return w.Flush()
+}
+
+// healthStatus returns status of a check, passed optional checks are reported as optional
+// unless complete.
+func healthStatus(ok, complete bool) string {
+	switch {
+	case !ok:
+		return "FAIL"
+	case !complete:
+		return "optional"
+	default:
+		return "ok"
+	}
+}
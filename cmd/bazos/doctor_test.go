@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+func TestCheckFixtures(t *testing.T) {
+	report, err := checkFixtures("../../bazos/testdata", bazos.DefaultSelectorProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range report.Pages {
+		if !page.OK() {
+			t.Errorf("%s page %s: expected healthy page, got %+v", page.Type, page.Source, page)
+		}
+	}
+	if len(report.Pages) != 11 || report.Site != "at,cz,pl,sk" {
+		t.Errorf("unexpected report of %d pages of %q", len(report.Pages), report.Site)
+	}
+}
+
+func TestCheckFixturesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"no manifest", "", "failed to read fixtures manifest"},
+		{"no pages", "pages: {}\n", "no pages"},
+		{"page type", "pages:\n  page.html: {type: seller, site: sk}\n", `invalid page type "seller"`},
+		{"site", "pages:\n  page.html: {type: ad, site: xx}\n", "page.html"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte("<html></html>"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if test.manifest != "" {
+				if err := os.WriteFile(filepath.Join(dir, fixturesManifest), []byte(test.manifest), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := checkFixtures(dir, bazos.DefaultSelectorProfile())
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/internal/fsutil"
+)
+
+func newFeedCmd() *cobra.Command {
+	var (
+		format   string
+		outFile  string
+		listOpts bazos.ListOptions
+	)
+	cmd := &cobra.Command{
+		Use:   "feed [query]",
+		Short: "Writes search results as RSS or Atom feed",
+		Long: `Writes ads matching the given query as RSS 2.0 or Atom feed, to stdout or to a file (--out).
+
+Running it periodically with --out lets any feed reader subscribe to a saved search.
+The same feed is served by the serve command at /api/feed.`,
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var write func(f *bazos.Feed, buf *bytes.Buffer) error
+			switch format {
+			case "rss":
+				write = func(f *bazos.Feed, buf *bytes.Buffer) error { return f.WriteRSS(buf) }
+			case "atom":
+				write = func(f *bazos.Feed, buf *bytes.Buffer) error { return f.WriteAtom(buf) }
+			default:
+				return fmt.Errorf("invalid feed format %q, expected rss or atom", format)
+			}
+			query, err := searchQueryFromFlags(cmd, args)
+			if err != nil {
+				return err
+			}
+			feed, err := client.NewFeed(cmd.Context(), query, listOpts)
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := write(feed, &buf); err != nil {
+				return err
+			}
+			if outFile == "" {
+				_, err := os.Stdout.Write(buf.Bytes())
+				return err
+			}
+			return fsutil.WriteFileAtomic(outFile, buf.Bytes(), 0o644)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "rss", "Feed format (rss, atom)")
+	cmd.Flags().StringVar(&outFile, "out", "", "File to write the feed to, replaced atomically (default stdout)")
+	cmd.Flags().IntVar(&listOpts.MaxPages, "max-pages", bazos.DefaultMaxPages, "Maximum number of result pages to fetch (-1 for no limit)")
+	cmd.Flags().IntVar(&listOpts.MaxResults, "limit", 0, "Maximum number of results (0 for no limit)")
+	cmd.Flags().BoolVar(&listOpts.CollapseReposts, "collapse", false, "Collapse reposts of the same item into the original ad (the oldest one), fetches all pages first")
+	addSearchQueryFlags(cmd)
+	return cmd
+}
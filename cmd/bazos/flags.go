@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"go.fabry.dev/fbot/bazos"
+)
+
+// parseSectionFlag parses section in format 'section[/category]'.
+func parseSectionFlag(s string) (*bazos.AdSection, error) {
+	sec, cat, _ := strings.Cut(strings.Trim(s, "/ "), "/")
+	if sec == "" {
+		return nil, fmt.Errorf("invalid section %q, expected format 'section[/category]'", s)
+	}
+	return &bazos.AdSection{
+		Section:  sec,
+		Category: cat,
+	}, nil
+}
+
+// parsePriceFlag parses price range in format 'min-max', either bound may be omitted.
+// A single value without '-' is both bounds, matching exactly that price; it must be
+// greater than zero, as zero bounds mean no limit.
+func parsePriceFlag(s string) (from, to int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, nil
+	}
+	min, max, isRange := strings.Cut(s, "-")
+	if !isRange {
+		max = min
+	}
+	if from, err = parsePriceBound(min); err != nil {
+		return 0, 0, fmt.Errorf("invalid price range %q: %w", s, err)
+	}
+	if to, err = parsePriceBound(max); err != nil {
+		return 0, 0, fmt.Errorf("invalid price range %q: %w", s, err)
+	}
+	if !isRange && from == 0 {
+		return 0, 0, fmt.Errorf("invalid price %q, a single price must be greater than zero", s)
+	}
+	if to > 0 && from > to {
+		return 0, 0, fmt.Errorf("invalid price range %q, minimum is greater than maximum", s)
+	}
+	return from, to, nil
+}
+
+func parsePriceBound(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("expected format 'min-max' with non-negative whole numbers")
+	}
+	return v, nil
+}
+
+// addSearchQueryFlags adds flags read by searchQueryFromFlags to cmd.
+func addSearchQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("category", "c", "", "Section to search within (format: 'section[/category]', see 'bazos sections')")
+	cmd.Flags().StringP("location", "l", "", "Postcode to search around")
+	cmd.Flags().IntP("vicinity", "v", bazos.DefaultVicinity, "Radius in km around --location")
+	cmd.Flags().StringP("price", "p", "", "Price range to search within (format: 'min-max', '100-', '-500', a single value for exactly that price)")
+	cmd.Flags().String("order", "newest", "Sort order provided by the site (newest, price-asc, price-desc)")
+	cmd.Flags().Bool("no-top", false, "Exclude promoted (TOP) ads")
+	cmd.Flags().Int("days", 0, "Only ads posted in the last N days (0 for no limit)")
+	cmd.Flags().StringSlice("exclude", nil, "Exclude ads containing any of the comma separated words")
+	cmd.Flags().String("match", "", "Regular expression the title or description must match")
+	cmd.Flags().Int("min-views", 0, "Only ads with at least N views")
+	cmd.Flags().Bool("photo-only", false, "Only ads with photo")
+}
+
+// searchQueryFromFlags builds search query from the search flags and query arguments.
+func searchQueryFromFlags(cmd *cobra.Command, args []string) (bazos.SearchQuery, error) {
+	q := bazos.SearchQuery{
+		Query:    strings.Join(args, " "),
+		Vicinity: bazos.DefaultVicinity,
+	}
+	flags := cmd.Flags()
+
+	if category, _ := flags.GetString("category"); category != "" {
+		section, err := parseSectionFlag(category)
+		if err != nil {
+			return q, err
+		}
+		q.Section = section
+	}
+
+	if location, _ := flags.GetString("location"); location != "" {
+		postCode, err := client.Site().NormalizePostCode(location)
+		if err != nil {
+			return q, fmt.Errorf("invalid location: %w", err)
+		}
+		q.Location = postCode
+	}
+
+	if flags.Changed("vicinity") {
+		if q.Location == "" {
+			return q, fmt.Errorf("--vicinity requires --location")
+		}
+		q.Vicinity, _ = flags.GetInt("vicinity")
+	}
+
+	price, _ := flags.GetString("price")
+	from, to, err := parsePriceFlag(price)
+	if err != nil {
+		return q, err
+	}
+	q.PriceFrom, q.PriceTo = from, to
+
+	order, _ := flags.GetString("order")
+	q.Sort = bazos.SortOrder(order)
+	if order == "newest" {
+		q.Sort = bazos.SortNewest
+	}
+	q.ExcludeTop, _ = flags.GetBool("no-top")
+	q.PostedWithinDays, _ = flags.GetInt("days")
+	q.ExcludeWords, _ = flags.GetStringSlice("exclude")
+	q.Match, _ = flags.GetString("match")
+	q.MinViews, _ = flags.GetInt("min-views")
+	q.PhotoOnly, _ = flags.GetBool("photo-only")
+
+	if err := q.Validate(client.Site()); err != nil {
+		return q, err
+	}
+	if q.Section != nil && q.Section.Category != "" {
+		catalog, err := loadCatalog(cmd.Context(), bazos.DefaultCatalogPath(client.Site()), []string{q.Section.Section}, false)
+		if err != nil {
+			// do not block searching when categories cannot be discovered
+			logrus.Warnf("cannot validate category: %v", err)
+		} else if err := bazos.ValidateCategory(q, catalog); err != nil {
+			return q, fmt.Errorf("%w (see 'bazos sections %s')", err, q.Section.Section)
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+func TestParsePriceFlag(t *testing.T) {
+	tests := []struct {
+		in       string
+		from, to int
+		err      bool
+	}{
+		{in: "", from: 0, to: 0},
+		{in: "100-500", from: 100, to: 500},
+		{in: " 100 - 500 ", from: 100, to: 500},
+		{in: "-500", from: 0, to: 500},
+		{in: "500-", from: 500, to: 0},
+		// a single value matches exactly that price
+		{in: "500", from: 500, to: 500},
+		{in: "0", err: true},
+		{in: "500-100", err: true},
+		{in: "abc", err: true},
+		{in: "1.5-2", err: true},
+		{in: "100-200-300", err: true},
+	}
+	for _, tt := range tests {
+		from, to, err := parsePriceFlag(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %d-%d", tt.in, from, to)
+			}
+			continue
+		}
+		if err != nil || from != tt.from || to != tt.to {
+			t.Errorf("%q: got %d-%d, %v, want %d-%d", tt.in, from, to, err, tt.from, tt.to)
+		}
+	}
+}
+
+func TestParseSectionFlag(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bazos.AdSection
+		err  bool
+	}{
+		{in: "elektro", want: bazos.AdSection{Section: "elektro"}},
+		{in: "elektro/pracky", want: bazos.AdSection{Section: "elektro", Category: "pracky"}},
+		{in: "/elektro/pracky/ ", want: bazos.AdSection{Section: "elektro", Category: "pracky"}},
+		{in: "", err: true},
+		{in: "/", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSectionFlag(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %+v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || *got != tt.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSearchQueryFromFlags(t *testing.T) {
+	client = bazos.NewClient()
+
+	tests := []struct {
+		name  string
+		flags []string
+		args  []string
+		check func(q bazos.SearchQuery) bool
+		err   bool
+	}{
+		{
+			name: "defaults",
+			args: []string{"pračka", "electrolux"},
+			check: func(q bazos.SearchQuery) bool {
+				return q.Query == "pračka electrolux" && q.Sort == bazos.SortNewest && q.Vicinity == bazos.DefaultVicinity
+			},
+		},
+		{
+			name:  "location",
+			flags: []string{"--location", "110 00", "--vicinity", "50"},
+			check: func(q bazos.SearchQuery) bool {
+				return q.Location == "11000" && q.Vicinity == 50
+			},
+		},
+		{
+			name:  "price and filters",
+			flags: []string{"--category", "elektro", "--price", "-500", "--no-top", "--min-views", "10"},
+			check: func(q bazos.SearchQuery) bool {
+				return q.Section != nil && q.Section.Section == "elektro" && q.PriceFrom == 0 && q.PriceTo == 500 &&
+					q.ExcludeTop && q.MinViews == 10
+			},
+		},
+		{name: "vicinity without location", flags: []string{"--vicinity", "50"}, err: true},
+		{name: "invalid location", flags: []string{"--location", "123"}, err: true},
+		{name: "unknown section", flags: []string{"--category", "nope"}, err: true},
+		{name: "invalid price", flags: []string{"--price", "500-100"}, err: true},
+		{name: "zero price", flags: []string{"--price", "0"}, err: true},
+		{name: "invalid order", flags: []string{"--order", "views"}, err: true},
+		{name: "invalid match", flags: []string{"--match", "("}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addSearchQueryFlags(cmd)
+			if err := cmd.ParseFlags(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			q, err := searchQueryFromFlags(cmd, tt.args)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error, got %+v", q)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(q) {
+				t.Errorf("unexpected query: %+v", q)
+			}
+		})
+	}
+}
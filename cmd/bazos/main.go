@@ -1,24 +1,49 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
 	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/archive"
 )
 
+// client is the bazos client configured by root command flags.
+var client *bazos.Client
+
 func main() {
-	var loglvl string
+	var (
+		loglvl    string
+		timeout   time.Duration
+		userAgent string
+		siteCode  string
+		phone     bool
+		download  bool
+		archDir   string
+		listOpts  bazos.ListOptions
+		policy    = bazos.DefaultCrawlPolicy
+		useCache  bool
+		cacheDir  string
+		cacheAge  time.Duration
+		cacheSize int64
+		selectors string
+		details   bool
+		enrich    bazos.EnrichOptions
+	)
 
 	rootCmd := &cobra.Command{
 		Use:          "bazos",
 		Short:        "A CLI app for bazos.sk",
-		Long:         `A CLI app for interacting with the popular advertising website bazos.sk`,
+		Long:         `A CLI app for interacting with the popular advertising website bazos.sk (and bazos.cz, bazos.pl, bazos.at)`,
 		SilenceUsage: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
 			lvl := logrus.InfoLevel
@@ -29,14 +54,68 @@ func main() {
 				}
 			}
 			logrus.SetLevel(lvl)
+			if err := output.validate(); err != nil {
+				return err
+			}
 			if logrus.IsLevelEnabled(logrus.DebugLevel) {
 				logrus.SetReportCaller(true)
 			}
+			site, err := bazos.LookupSite(siteCode)
+			if err != nil {
+				return err
+			}
+			opts := []bazos.ClientOption{
+				bazos.WithSite(site),
+				bazos.WithHTTPClient(&http.Client{Timeout: timeout}),
+				bazos.WithUserAgent(userAgent),
+				bazos.WithPhoneReveal(phone),
+				bazos.WithCrawlPolicy(policy),
+			}
+			if useCache {
+				disk, err := bazos.NewDiskCache(cacheDir)
+				if err != nil {
+					return fmt.Errorf("failed to open cache: %w", err)
+				}
+				if removed, err := disk.Prune(cacheAge, cacheSize<<20); err != nil {
+					logrus.Warnf("failed to prune cache: %v", err)
+				} else if removed > 0 {
+					logrus.Debugf("pruned %d cache entries", removed)
+				}
+				cache := bazos.NewTieredCache(bazos.NewMemoryCache(0), disk)
+				opts = append(opts, bazos.WithCache(cache, bazos.DefaultCacheTTL))
+			}
+			if selectors != "" {
+				profile, err := bazos.LoadSelectorProfile(selectors)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, bazos.WithSelectors(profile))
+			}
+			client = bazos.NewClient(opts...)
 			return nil
 		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if useCache {
+				stats := client.CacheStats()
+				logrus.Debugf("cache: %d hits, %d revalidated, %d misses", stats.Hits, stats.Revalidated, stats.Misses)
+			}
+		},
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&loglvl, "loglvl", "L", "", "Set logging level (trace, debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVarP(&siteCode, "site", "s", bazos.DefaultSite.Code, "Country site to use (sk, cz, pl, at)")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout for HTTP requests")
+	rootCmd.PersistentFlags().StringVar(&userAgent, "user-agent", bazos.DefaultUserAgent, "User-Agent header for HTTP requests")
+	rootCmd.PersistentFlags().DurationVar(&policy.MinInterval, "rate-limit", policy.MinInterval, "Minimum delay between requests to the same host")
+	rootCmd.PersistentFlags().IntVar(&policy.MaxRetries, "retries", policy.MaxRetries, "Number of retries for failed requests")
+	rootCmd.PersistentFlags().BoolVar(&policy.RespectRobots, "robots", policy.RespectRobots, "Respect robots.txt rules")
+	rootCmd.PersistentFlags().BoolVar(&useCache, "cache", false, "Cache fetched pages in memory and in --cache-dir")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", bazos.DefaultCacheDir(), "Directory of cached pages")
+	rootCmd.PersistentFlags().DurationVar(&cacheAge, "cache-max-age", bazos.DefaultDiskCacheMaxAge, "Remove cached pages older than this (0 for no limit)")
+	rootCmd.PersistentFlags().Int64Var(&cacheSize, "cache-max-size", bazos.DefaultDiskCacheMaxSize>>20, "Maximum size of --cache-dir in MiB, oldest pages are removed first (0 for no limit)")
+	rootCmd.PersistentFlags().StringVar(&selectors, "selectors", "", "YAML file overriding selectors of the parsers, see bazos/selectors.yaml")
+	rootCmd.PersistentFlags().StringVarP(&output.Format, "output", "o", output.Format, "Output format of ads ("+strings.Join(outputFormats, ", ")+")")
+	rootCmd.PersistentFlags().StringSliceVar(&output.Fields, "fields", nil, "Comma separated ad fields to output, e.g. 'ID,Title,Price' (not used by template format)")
 
 	// Search command
 	searchCmd := &cobra.Command{
@@ -46,22 +125,75 @@ func main() {
 		SilenceUsage: true,
 		Args:         cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			query := strings.Join(args, " ")
-			ads, err := bazos.Search(query)
+			query, err := searchQueryFromFlags(cmd, args)
 			if err != nil {
 				return err
 			}
+			it := client.IterAds(cmd.Context(), query, listOpts)
 
-			for i, ad := range ads {
-				fmt.Printf("- #%d - %v - %v€ (ID: %v) - %v\n", i+1, ad.Title, ad.Price, ad.ID, ad.Link)
+			printer, err := output.newPrinter(os.Stdout, false)
+			if err != nil {
+				return err
+			}
+			i := 0
+			if output.Sort != "" || details {
+				// sorting and details need all results before printing
+				ads, err := it.All()
+				if err != nil {
+					return err
+				}
+				if details {
+					errs, err := client.Enrich(cmd.Context(), ads, enrich)
+					if err != nil {
+						return err
+					}
+					for _, e := range errs {
+						logrus.Warnf("failed to fetch details: %v", e)
+					}
+				}
+				if output.Sort != "" {
+					if err := sortAds(ads, output.Sort); err != nil {
+						return err
+					}
+				}
+				for _, ad := range ads {
+					if err := printer.Print(ad); err != nil {
+						return err
+					}
+				}
+				i = len(ads)
+			} else {
+				for it.Next() {
+					i++
+					if err := printer.Print(it.Ad()); err != nil {
+						it.Stop()
+						return err
+					}
+				}
+				if err := it.Err(); err != nil {
+					return err
+				}
+			}
+			if err := printer.Close(); err != nil {
+				return err
+			}
+			if it.Truncated() {
+				total := 0
+				if page := it.Page(); page != nil {
+					total = page.Total
+				}
+				fmt.Fprintf(os.Stderr, "results truncated: %s (use --max-pages/--limit)\n", truncatedSummary(i, total, it.Filtered()))
 			}
 			return nil
 		},
 	}
-	searchCmd.Flags().StringP("category", "c", "", "Category to search within (format: 'category/section')")
-	searchCmd.Flags().StringP("location", "l", "", "Location to search in")
-	searchCmd.Flags().IntP("vicinity", "v", 0, "Radius in km for vicinity search")
-	searchCmd.Flags().StringP("price", "p", "", "Price range to search within (format: 'min-max')")
+	searchCmd.Flags().IntVar(&listOpts.MaxPages, "max-pages", bazos.DefaultMaxPages, "Maximum number of result pages to fetch (-1 for no limit)")
+	searchCmd.Flags().IntVar(&listOpts.MaxResults, "limit", 0, "Maximum number of results (0 for no limit)")
+	searchCmd.Flags().BoolVar(&listOpts.CollapseReposts, "collapse", false, "Collapse reposts of the same item into the original ad (the oldest one), fetches all pages first")
+	searchCmd.Flags().StringVar(&output.Sort, "sort", "", "Sort results by price, date or views, prefix with '-' for descending order (waits for all results)")
+	searchCmd.Flags().BoolVar(&details, "details", false, "Fetch detail pages of results for full descriptions, all images and seller (waits for all results)")
+	searchCmd.Flags().IntVar(&enrich.Workers, "workers", bazos.DefaultEnrichWorkers, "Number of detail pages fetched in parallel by --details")
+	addSearchQueryFlags(searchCmd)
 
 	rootCmd.AddCommand(searchCmd)
 
@@ -74,28 +206,57 @@ func main() {
 		Args:         cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			id := args[0]
-			ad, err := bazos.GetAdById(id)
+			ad, err := client.GetAdById(cmd.Context(), id)
 			if err != nil {
 				return err
 			}
-			fmt.Printf("%+v\n", toYaml(ad))
+			opts := output
+			if !cmd.Flags().Changed("output") {
+				// single ad reads better as YAML than as table
+				opts.Format = "yaml"
+			}
+			printer, err := opts.newPrinter(os.Stdout, true)
+			if err != nil {
+				return err
+			}
+			if err := printer.Print(*ad); err != nil {
+				return err
+			}
+			if err := printer.Close(); err != nil {
+				return err
+			}
+			if download {
+				arch, err := archive.New(archDir, client)
+				if err != nil {
+					return err
+				}
+				m, err := arch.Save(cmd.Context(), ad)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "archived ad with %d images to %s\n", len(m.Images), arch.AdDir(ad))
+			}
 			return nil
 		},
 	}
+	getCmd.Flags().BoolVar(&phone, "phone", false, "Reveal seller phone number")
+	getCmd.Flags().BoolVar(&download, "download-images", false, "Archive the ad with its full resolution images")
+	getCmd.Flags().StringVar(&archDir, "archive", archive.DefaultDir(), "Archive directory used by --download-images")
 
 	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(newWatchCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newSectionsCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newFeedCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newSellerCmd())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
-
-func toYaml(v any) string {
-	var buf strings.Builder
-	enc := yaml.NewEncoder(&buf)
-	if err := enc.Encode(v); err != nil {
-		return err.Error()
-	}
-	return buf.String()
-}
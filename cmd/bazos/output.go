@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+// outputFormats lists supported values of the --output flag.
+var outputFormats = []string{"table", "json", "jsonl", "yaml", "csv", "template=<go template>"}
+
+// defaultTableFields are the fields printed by table format when --fields is not set.
+var defaultTableFields = []string{"ID", "Date", "Price", "Location", "Title", "Link"}
+
+// outputOptions are the options of ad output set by root command flags.
+type outputOptions struct {
+	// Format is one of outputFormats.
+	Format string
+	// Fields selects printed fields of ads.
+	Fields []string
+	// Sort is the sort key (price, date, views), prefixed with '-' for descending order.
+	Sort string
+}
+
+// output is the ad output configured by root command flags.
+var output = outputOptions{Format: "table"}
+
+// adPrinter writes ads in the output format.
+type adPrinter interface {
+	// Print writes single ad, formats writing whole documents may buffer ads until Close.
+	Print(ad bazos.Ad) error
+	// Close flushes buffered output.
+	Close() error
+}
+
+// validate checks format, fields and sort key.
+func (o outputOptions) validate() error {
+	if _, err := o.newPrinter(io.Discard, false); err != nil {
+		return err
+	}
+	if _, _, err := parseSortKey(o.Sort); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newPrinter returns printer writing ads to w, single prints one ad as object instead of list.
+func (o outputOptions) newPrinter(w io.Writer, single bool) (adPrinter, error) {
+	fields, err := resolveFields(o.Fields)
+	if err != nil {
+		return nil, err
+	}
+	format, arg, _ := strings.Cut(o.Format, "=")
+	switch format {
+	case "", "table":
+		if fields == nil {
+			fields, _ = resolveFields(defaultTableFields)
+		}
+		return &tablePrinter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), fields: fields}, nil
+	case "json":
+		return &documentPrinter{w: w, fields: fields, single: single, encode: encodeJSON}, nil
+	case "yaml":
+		return &documentPrinter{w: w, fields: fields, single: single, encode: encodeYAML}, nil
+	case "jsonl":
+		return &jsonlPrinter{enc: json.NewEncoder(w), fields: fields}, nil
+	case "csv":
+		if fields == nil {
+			fields = allFields()
+		}
+		return &csvPrinter{w: csv.NewWriter(w), fields: fields}, nil
+	case "template":
+		if arg == "" {
+			return nil, fmt.Errorf("empty template, use --output 'template={{.Title}}'")
+		}
+		tmpl, err := template.New("output").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		return &templatePrinter{w: w, tmpl: tmpl}, nil
+	}
+	return nil, fmt.Errorf("unsupported output format %q, supported formats: %s", o.Format, strings.Join(outputFormats, ", "))
+}
+
+// adField is a selectable field of bazos.Ad.
+type adField struct {
+	Name  string
+	index int
+}
+
+func (f adField) value(ad bazos.Ad) any {
+	return reflect.ValueOf(ad).Field(f.index).Interface()
+}
+
+// allFields returns all exported fields of bazos.Ad.
+func allFields() []adField {
+	t := reflect.TypeOf(bazos.Ad{})
+	var fields []adField
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			fields = append(fields, adField{Name: t.Field(i).Name, index: i})
+		}
+	}
+	return fields
+}
+
+// resolveFields matches field names case-insensitively, nil names select no specific fields.
+func resolveFields(names []string) ([]adField, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	all := allFields()
+	var fields []adField
+	for _, name := range names {
+		f, ok := lookupField(all, strings.TrimSpace(name))
+		if !ok {
+			known := make([]string, len(all))
+			for i, f := range all {
+				known[i] = f.Name
+			}
+			return nil, fmt.Errorf("unknown field %q, available fields: %s", name, strings.Join(known, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func lookupField(fields []adField, name string) (adField, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return adField{}, false
+}
+
+// formatField formats field value as single-line text for table and CSV output.
+func formatField(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return formatField(*v)
+	case *bazos.AdSection:
+		if v == nil {
+			return ""
+		}
+		if v.Category == "" {
+			return v.Section
+		}
+		return v.Section + "/" + v.Category
+	case []string:
+		return strings.Join(v, " ")
+	case []bazos.ParseError:
+		var parts []string
+		for _, e := range v {
+			parts = append(parts, e.Field)
+		}
+		return strings.Join(parts, " ")
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	}
+	return fmt.Sprint(v)
+}
+
+// selectFields returns ad, or its selected fields in order, for document formats.
+func selectFields(ad bazos.Ad, fields []adField) any {
+	if fields == nil {
+		return ad
+	}
+	obj := make(orderedObject, 0, len(fields))
+	for _, f := range fields {
+		obj = append(obj, objectField{f.Name, f.value(ad)})
+	}
+	return obj
+}
+
+type objectField struct {
+	Name  string
+	Value any
+}
+
+// orderedObject encodes fields as object keeping their order, YAML keys are lowercase
+// like the default YAML encoding of structs.
+type orderedObject []objectField
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Quote(f.Name))
+		buf.WriteByte(':')
+		v, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o orderedObject) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range o {
+		var value yaml.Node
+		if err := value.Encode(f.Value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(f.Name)}, &value)
+	}
+	return node, nil
+}
+
+type tablePrinter struct {
+	w      *tabwriter.Writer
+	fields []adField
+	header bool
+}
+
+func (p *tablePrinter) Print(ad bazos.Ad) error {
+	if !p.header {
+		p.header = true
+		names := make([]string, len(p.fields))
+		for i, f := range p.fields {
+			names[i] = strings.ToUpper(f.Name)
+		}
+		fmt.Fprintln(p.w, strings.Join(names, "\t"))
+	}
+	values := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		values[i] = formatField(f.value(ad))
+	}
+	_, err := fmt.Fprintln(p.w, strings.Join(values, "\t"))
+	return err
+}
+
+func (p *tablePrinter) Close() error {
+	return p.w.Flush()
+}
+
+type csvPrinter struct {
+	w      *csv.Writer
+	fields []adField
+	header bool
+}
+
+func (p *csvPrinter) Print(ad bazos.Ad) error {
+	if !p.header {
+		p.header = true
+		names := make([]string, len(p.fields))
+		for i, f := range p.fields {
+			names[i] = f.Name
+		}
+		if err := p.w.Write(names); err != nil {
+			return err
+		}
+	}
+	values := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		values[i] = formatField(f.value(ad))
+	}
+	if err := p.w.Write(values); err != nil {
+		return err
+	}
+	p.w.Flush()
+	return p.w.Error()
+}
+
+func (p *csvPrinter) Close() error {
+	p.w.Flush()
+	return p.w.Error()
+}
+
+type jsonlPrinter struct {
+	enc    *json.Encoder
+	fields []adField
+}
+
+func (p *jsonlPrinter) Print(ad bazos.Ad) error {
+	return p.enc.Encode(selectFields(ad, p.fields))
+}
+
+func (p *jsonlPrinter) Close() error {
+	return nil
+}
+
+// documentPrinter buffers ads and writes them as single JSON or YAML document.
+type documentPrinter struct {
+	w      io.Writer
+	fields []adField
+	single bool
+	encode func(w io.Writer, v any) error
+	ads    []any
+}
+
+func (p *documentPrinter) Print(ad bazos.Ad) error {
+	p.ads = append(p.ads, selectFields(ad, p.fields))
+	return nil
+}
+
+func (p *documentPrinter) Close() error {
+	if p.single && len(p.ads) == 1 {
+		return p.encode(p.w, p.ads[0])
+	}
+	if p.ads == nil {
+		p.ads = []any{}
+	}
+	return p.encode(p.w, p.ads)
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func encodeYAML(w io.Writer, v any) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+type templatePrinter struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func (p *templatePrinter) Print(ad bazos.Ad) error {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, ad); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err := p.w.Write(buf.Bytes())
+	return err
+}
+
+func (p *templatePrinter) Close() error {
+	return nil
+}
+
+// parseSortKey parses sort key in format '[-]key', '-' means descending order.
+func parseSortKey(s string) (key string, desc bool, err error) {
+	key = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(key, "-") {
+		key, desc = key[1:], true
+	}
+	switch key {
+	case "", "price", "date", "views":
+		return key, desc, nil
+	}
+	return "", false, fmt.Errorf("unsupported sort key %q, supported keys: price, date, views (prefix with '-' for descending order)", s)
+}
+
+// sortAds sorts ads by sort key, see parseSortKey.
+func sortAds(ads []bazos.Ad, sortKey string) error {
+	key, desc, err := parseSortKey(sortKey)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "price":
+		bazos.SortByPrice(ads, desc)
+	case "date":
+		// bumped ads sort by the date they were listed again
+		sort.SliceStable(ads, func(i, j int) bool {
+			if desc {
+				return ads[i].Listed().After(ads[j].Listed())
+			}
+			return ads[i].Listed().Before(ads[j].Listed())
+		})
+	case "views":
+		sort.SliceStable(ads, func(i, j int) bool {
+			if desc {
+				return ads[i].Views > ads[j].Views
+			}
+			return ads[i].Views < ads[j].Views
+		})
+	}
+	return nil
+}
+
+// truncatedSummary describes shown ads of truncated results. The total reported by the site
+// counts ads before local filters, so only the shown ads are counted for filtered results.
+func truncatedSummary(shown, total int, filtered bool) string {
+	if filtered {
+		// next pages may have no ads passing the filters
+		return fmt.Sprintf("showing %d ads, more may be available", shown)
+	}
+	if total <= shown {
+		return fmt.Sprintf("showing %d ads, more are available", shown)
+	}
+	return fmt.Sprintf("showing %d of %d ads", shown, total)
+}
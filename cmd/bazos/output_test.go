@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+func testAds() []bazos.Ad {
+	bumped := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	return []bazos.Ad{
+		{
+			ID:       "1",
+			Title:    "Pračka  Electrolux",
+			Date:     time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC),
+			Price:    bazos.Price{Amount: 3500, Currency: "Kč", Kind: bazos.PriceKindFixed},
+			Location: "Praha",
+			Views:    10,
+		},
+		{
+			ID:       "2",
+			Title:    "Sušička",
+			Date:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			Bumped:   &bumped,
+			Price:    bazos.Price{Amount: 1200, Currency: "Kč", Kind: bazos.PriceKindFixed},
+			Location: "Brno",
+			Views:    30,
+		},
+		{
+			ID:       "3",
+			Title:    "Myčka",
+			Date:     time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			Price:    bazos.Price{Kind: bazos.PriceKindNegotiable},
+			Location: "Ostrava",
+			Views:    20,
+		},
+	}
+}
+
+func adIDs(ads []bazos.Ad) string {
+	ids := make([]string, len(ads))
+	for i, ad := range ads {
+		ids[i] = ad.ID
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestResolveFields(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+		err   bool
+	}{
+		{names: nil, want: ""},
+		{names: []string{"id", " TITLE ", "Price"}, want: "ID Title Price"},
+		{names: []string{"bumped", "id"}, want: "Bumped ID"},
+		{names: []string{"id", "nope"}, err: true},
+	}
+	for _, tt := range tests {
+		fields, err := resolveFields(tt.names)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.names)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.names, err)
+			continue
+		}
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f.Name
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestOrderedObject(t *testing.T) {
+	obj := orderedObject{{"Title", "a"}, {"ID", "1"}, {"Views", 3}}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Title":"a","ID":"1","Views":3}`; string(data) != want {
+		t.Errorf("JSON: got %s, want %s", data, want)
+	}
+
+	data, err = yaml.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "title: a\nid: \"1\"\nviews: 3\n"; string(data) != want {
+		t.Errorf("YAML: got %q, want %q", data, want)
+	}
+}
+
+func TestPrinters(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		fields []string
+		want   string
+	}{
+		{
+			name:   "table",
+			format: "table",
+			fields: []string{"ID", "Date", "Bumped", "Title"},
+			want: "ID  DATE        BUMPED      TITLE\n" +
+				"1   2024-01-14              Pračka Electrolux\n" +
+				"2   2024-01-02  2024-01-20  Sušička\n",
+		},
+		{
+			name:   "csv",
+			format: "csv",
+			fields: []string{"ID", "Price", "Location"},
+			want: "ID,Price,Location\n" +
+				"1,3500 Kč,Praha\n" +
+				"2,1200 Kč,Brno\n",
+		},
+		{
+			name:   "template",
+			format: "template={{.ID}}: {{.Title}}",
+			want:   "1: Pračka  Electrolux\n2: Sušička\n",
+		},
+		{
+			name:   "jsonl",
+			format: "jsonl",
+			fields: []string{"ID", "Views"},
+			want:   "{\"ID\":\"1\",\"Views\":10}\n{\"ID\":\"2\",\"Views\":30}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printer, err := outputOptions{Format: tt.format, Fields: tt.fields}.newPrinter(&buf, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, ad := range testAds()[:2] {
+				if err := printer.Print(ad); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := printer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPrinterInvalid(t *testing.T) {
+	for _, o := range []outputOptions{
+		{Format: "xml"},
+		{Format: "template="},
+		{Format: "template={{.Title"},
+		{Format: "csv", Fields: []string{"nope"}},
+	} {
+		if _, err := o.newPrinter(&bytes.Buffer{}, false); err == nil {
+			t.Errorf("%+v: expected error", o)
+		}
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		key  string
+		desc bool
+		err  bool
+	}{
+		{in: "", key: ""},
+		{in: "price", key: "price"},
+		{in: "-Date", key: "date", desc: true},
+		{in: " views ", key: "views"},
+		{in: "title", err: true},
+		{in: "--price", err: true},
+	}
+	for _, tt := range tests {
+		key, desc, err := parseSortKey(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || key != tt.key || desc != tt.desc {
+			t.Errorf("%q: got %q, %v, %v, want %q, %v", tt.in, key, desc, err, tt.key, tt.desc)
+		}
+	}
+}
+
+func TestSortAds(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "", want: "1 2 3"},
+		// ads with unknown price sort last in both orders
+		{key: "price", want: "2 1 3"},
+		{key: "-price", want: "1 2 3"},
+		// ad 2 was bumped after ad 1 was posted
+		{key: "date", want: "3 1 2"},
+		{key: "-date", want: "2 1 3"},
+		{key: "views", want: "1 3 2"},
+		{key: "-views", want: "2 3 1"},
+	}
+	for _, tt := range tests {
+		ads := testAds()
+		if err := sortAds(ads, tt.key); err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.key, err)
+			continue
+		}
+		if got := adIDs(ads); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.key, got, tt.want)
+		}
+	}
+	if err := sortAds(testAds(), "title"); err == nil {
+		t.Error("expected error for unsupported sort key")
+	}
+}
+
+func TestTruncatedSummary(t *testing.T) {
+	tests := []struct {
+		shown, total int
+		filtered     bool
+		want         string
+	}{
+		{20, 57, false, "showing 20 of 57 ads"},
+		{20, 57, true, "showing 20 ads, more may be available"},
+		{20, 0, false, "showing 20 ads, more are available"},
+	}
+	for _, test := range tests {
+		if got := truncatedSummary(test.shown, test.total, test.filtered); got != test.want {
+			t.Errorf("truncatedSummary(%d, %d, %v) = %q, want %q", test.shown, test.total, test.filtered, got, test.want)
+		}
+	}
+}
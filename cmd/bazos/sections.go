@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+// catalogMaxAge is the age after which the cached catalog is discovered again.
+const catalogMaxAge = 7 * 24 * time.Hour
+
+func newSectionsCmd() *cobra.Command {
+	var (
+		catalogPath string
+		refresh     bool
+		all         bool
+	)
+	cmd := &cobra.Command{
+		Use:   "sections [section...]",
+		Short: "Lists sections and their categories",
+		Long: `Lists sections of the site. Categories of the given sections (or all sections with --all)
+are discovered from the site and cached in the catalog file (--catalog) for a week.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			site := client.Site()
+			if all {
+				args = nil
+				for _, s := range site.Sections {
+					if s != bazos.AnySection {
+						args = append(args, s)
+					}
+				}
+			}
+			if catalogPath == "" {
+				catalogPath = bazos.DefaultCatalogPath(site)
+			}
+
+			catalog := &bazos.Catalog{Site: site.Code}
+			if len(args) == 0 {
+				for _, s := range site.Sections {
+					if s != bazos.AnySection {
+						catalog.Sections = append(catalog.Sections, bazos.CatalogSection{Slug: s, Name: site.SectionName(s)})
+					}
+				}
+			} else {
+				var err error
+				catalog, err = loadCatalog(cmd.Context(), catalogPath, args, refresh)
+				if err != nil {
+					return err
+				}
+			}
+			return printCatalog(catalog, args)
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "Discover categories of all sections")
+	cmd.Flags().StringVar(&catalogPath, "catalog", "", "Catalog cache file (default is in user cache directory)")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "Discover categories again ignoring the cache")
+	return cmd
+}
+
+// loadCatalog loads cached catalog and discovers categories of sections missing in it,
+// refreshing all of them if the cache is stale or refresh is set. The catalog is saved back to path.
+func loadCatalog(ctx context.Context, path string, sections []string, refresh bool) (*bazos.Catalog, error) {
+	site := client.Site()
+	catalog, err := bazos.LoadCatalog(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logrus.Warnf("ignoring catalog cache: %v", err)
+		}
+		catalog = &bazos.Catalog{Site: site.Code}
+	}
+	if catalog.Site != site.Code || time.Since(catalog.Updated) > catalogMaxAge {
+		catalog = &bazos.Catalog{Site: site.Code}
+	}
+
+	updated := false
+	for _, s := range sections {
+		if cs, ok := catalog.Section(s); ok && len(cs.Categories) > 0 && !refresh {
+			continue
+		}
+		cs, err := client.CatalogSection(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		catalog.SetSection(cs)
+		updated = true
+	}
+	if updated {
+		if catalog.Updated.IsZero() || refresh {
+			catalog.Updated = time.Now()
+		}
+		if err := catalog.Save(path); err != nil {
+			logrus.Warnf("failed to save catalog cache: %v", err)
+		}
+	}
+	return catalog, nil
+}
+
+func printCatalog(catalog *bazos.Catalog, sections []string) error {
+	format, _, _ := strings.Cut(output.Format, "=")
+	switch format {
+	case "json":
+		return encodeJSON(os.Stdout, catalog)
+	case "yaml":
+		return encodeYAML(os.Stdout, catalog)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(sections) == 0 {
+		fmt.Fprintln(w, "SECTION\tNAME")
+		for _, s := range catalog.Sections {
+			fmt.Fprintf(w, "%s\t%s\n", s.Slug, s.Name)
+		}
+		return w.Flush()
+	}
+	fmt.Fprintln(w, "SECTION/CATEGORY\tNAME\tADS")
+	for _, slug := range sections {
+		s, ok := catalog.Section(slug)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t\n", s.Slug, s.Name)
+		for _, c := range s.Categories {
+			count := ""
+			if c.Count > 0 {
+				count = fmt.Sprint(c.Count)
+			}
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\n", s.Slug, c.Slug, c.Name, count)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos"
+)
+
+func newSellerCmd() *cobra.Command {
+	var listOpts bazos.ListOptions
+	cmd := &cobra.Command{
+		Use:   "seller [ad id | profile url]",
+		Short: "Shows seller profile and active ads of the seller",
+		Long: `Shows ratings and active ads of the seller of the ad with the given ID, or of the seller
+profile at the given URL (the UserLink of an ad).
+
+Many active ads of a seller posing as private suggest a dealer, ratings help to check
+the seller before contacting them.`,
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				seller *bazos.Seller
+				err    error
+			)
+			if strings.Contains(args[0], "://") {
+				seller, err = client.GetSeller(cmd.Context(), args[0], listOpts)
+			} else {
+				var ad *bazos.Ad
+				if ad, err = client.GetAdById(cmd.Context(), args[0]); err != nil {
+					return err
+				}
+				seller, err = client.GetAdSeller(cmd.Context(), ad, listOpts)
+			}
+			if err != nil {
+				return err
+			}
+			if output.Sort != "" {
+				if err := sortAds(seller.Ads, output.Sort); err != nil {
+					return err
+				}
+			}
+			return printSeller(seller)
+		},
+	}
+	cmd.Flags().IntVar(&listOpts.MaxPages, "max-pages", bazos.DefaultMaxPages, "Maximum number of pages of seller ads to fetch (-1 for no limit)")
+	cmd.Flags().IntVar(&listOpts.MaxResults, "limit", 0, "Maximum number of seller ads (0 for no limit)")
+	cmd.Flags().StringVar(&output.Sort, "sort", "", "Sort ads by price, date or views, prefix with '-' for descending order")
+	return cmd
+}
+
+// printSeller prints seller as document in json and yaml format, otherwise the summary
+// is followed by ads in the output format, or written to stderr if the format is not table.
+func printSeller(seller *bazos.Seller) error {
+	format, _, _ := strings.Cut(output.Format, "=")
+	switch format {
+	case "json":
+		return encodeJSON(os.Stdout, seller)
+	case "yaml":
+		return encodeYAML(os.Stdout, seller)
+	}
+
+	var summary io.Writer = os.Stdout
+	if format != "" && format != "table" {
+		summary = os.Stderr
+	}
+	w := tabwriter.NewWriter(summary, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Seller:\t%s (%s)\n", seller.Name, seller.ID)
+	fmt.Fprintf(w, "Profile:\t%s\n", seller.Link)
+	fmt.Fprintf(w, "Ratings:\t+%d / -%d\n", seller.PositiveRatings, seller.NegativeRatings)
+	fmt.Fprintf(w, "Active ads:\t%d\n", seller.Total)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(seller.Ads) == 0 {
+		return nil
+	}
+	fmt.Fprintln(summary)
+
+	printer, err := output.newPrinter(os.Stdout, false)
+	if err != nil {
+		return err
+	}
+	for _, ad := range seller.Ads {
+		if err := printer.Print(ad); err != nil {
+			return err
+		}
+	}
+	if err := printer.Close(); err != nil {
+		return err
+	}
+	if seller.Truncated {
+		fmt.Fprintf(os.Stderr, "results truncated: showing %d of %d ads (use --max-pages/--limit)\n", len(seller.Ads), seller.Total)
+	}
+	return nil
+}
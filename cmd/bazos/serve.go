@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos/api"
+	"go.fabry.dev/fbot/bazos/watch"
+)
+
+func newServeCmd() *cobra.Command {
+	var (
+		addr       string
+		cacheTTL   time.Duration
+		cacheSize  int
+		configFile string
+		storeDir   string
+	)
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serves HTTP/JSON API",
+		Long: `Serves search, ads, sections and results of saved watches over HTTP/JSON API.
+
+The OpenAPI description is served at /api/openapi.json. Results of watches (--config)
+are read from the store of the watch command (--store).`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server := &api.Server{Client: client, CacheTTL: cacheTTL, CacheEntries: cacheSize}
+			if configFile != "" {
+				cfg, err := watch.LoadConfig(configFile)
+				if err != nil {
+					return err
+				}
+				server.Watches = cfg.Watches
+				if server.WatchStore, err = watch.NewStore(storeDir); err != nil {
+					return err
+				}
+			}
+
+			httpServer := &http.Server{
+				Addr:              addr,
+				Handler:           server,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				<-cmd.Context().Done()
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = httpServer.Shutdown(ctx)
+			}()
+
+			logrus.Infof("serving API on %s", addr)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", "localhost:8080", "Address to listen on")
+	cmd.Flags().DurationVar(&cacheTTL, "cache-ttl", api.DefaultCacheTTL, "Time responses are cached (negative to disable)")
+	cmd.Flags().IntVar(&cacheSize, "cache-entries", api.DefaultCacheEntries, "Maximum number of cached responses")
+	cmd.Flags().StringVar(&configFile, "config", "", "YAML file with watches")
+	cmd.Flags().StringVar(&storeDir, "store", watch.DefaultStoreDir(), "Directory of seen ads stored by the watch command")
+	return cmd
+}
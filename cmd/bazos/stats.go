@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/history"
+)
+
+func newStatsCmd() *cobra.Command {
+	var (
+		historyDir string
+		offline    bool
+		showAds    bool
+		maxPages   int
+	)
+	cmd := &cobra.Command{
+		Use:   "stats [query]",
+		Short: "Shows price history and market statistics",
+		Long: `Records current ads matching the query into local history and shows market statistics
+computed from all recorded observations: price percentiles, time on market, view velocity
+and price drop frequency.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query, err := searchQueryFromFlags(cmd, args)
+			if err != nil {
+				return err
+			}
+			if query.Query == "" && query.Section == nil {
+				return fmt.Errorf("query or section is required")
+			}
+
+			store, err := history.NewStore(historyDir)
+			if err != nil {
+				return err
+			}
+			series := history.SeriesName(query)
+
+			if !offline {
+				ads, err := client.IterAds(cmd.Context(), query, bazos.ListOptions{MaxPages: maxPages}).All()
+				if err != nil {
+					return err
+				}
+				if err := store.Record(series, ads, time.Now()); err != nil {
+					return fmt.Errorf("recording history failed: %w", err)
+				}
+			}
+
+			observations, err := store.Load(series)
+			if err != nil {
+				return err
+			}
+			stats := history.Compute(observations)
+			printStats(series, stats, showAds)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&historyDir, "history", history.DefaultStoreDir(), "Directory of the history store")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Only use recorded history, do not fetch current ads")
+	cmd.Flags().BoolVar(&showAds, "ads", false, "Show statistics of individual active ads")
+	cmd.Flags().IntVar(&maxPages, "max-pages", bazos.DefaultMaxPages, "Maximum number of result pages to fetch (-1 for no limit)")
+	addSearchQueryFlags(cmd)
+	return cmd
+}
+
+func printStats(series string, stats history.Stats, showAds bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	p := stats.Prices
+	fmt.Fprintf(w, "Series:\t%s\n", series)
+	fmt.Fprintf(w, "Ads:\t%d (%d active, %d observations)\n", stats.Ads, stats.Active, stats.Observations)
+	fmt.Fprintf(w, "Prices:\t%d known\n", p.Count)
+	if p.Count > 0 {
+		fmt.Fprintf(w, "  min / p10 / p25:\t%.0f / %.0f / %.0f\n", p.Min, p.P10, p.P25)
+		fmt.Fprintf(w, "  median:\t%.0f\n", p.Median)
+		fmt.Fprintf(w, "  p75 / p90 / max:\t%.0f / %.0f / %.0f\n", p.P75, p.P90, p.Max)
+	}
+	fmt.Fprintf(w, "Median time on market:\t%v\n", stats.MedianTimeOnMarket.Round(time.Hour))
+	fmt.Fprintf(w, "Median views per day:\t%.1f\n", stats.MedianViewsPerDay)
+	fmt.Fprintf(w, "Price drops:\t%d (%.0f%% of ads)\n", stats.PriceDrops, stats.PriceDropRate*100)
+
+	if !showAds {
+		return
+	}
+	ads := append([]history.AdStats(nil), stats.AdStats...)
+	sort.SliceStable(ads, func(i, j int) bool { return ads[i].PricePercentile < ads[j].PricePercentile })
+	fmt.Fprintf(w, "\nID\tPRICE\tPERCENTILE\tON MARKET\tVIEWS/DAY\tDROPS\tTITLE\n")
+	for _, as := range ads {
+		if !as.Active {
+			continue
+		}
+		price := "-"
+		if as.HasPrice {
+			price = fmt.Sprintf("%.0f", as.Price)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.0f%%\t%v\t%.1f\t%d\t%s\n",
+			as.AdID, price, as.PricePercentile, as.TimeOnMarket.Round(time.Hour), as.ViewsPerDay, as.PriceDrops, as.Title)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go.fabry.dev/fbot/bazos"
+	"go.fabry.dev/fbot/bazos/history"
+	"go.fabry.dev/fbot/bazos/watch"
+)
+
+func newWatchCmd() *cobra.Command {
+	var (
+		configFile string
+		storeDir   string
+		interval   time.Duration
+		once       bool
+		maxPages   int
+		historyDir string
+		collapse   bool
+		notifyAll  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "watch [query]",
+		Short: "Watches saved searches for changes",
+		Long: `Periodically runs saved searches and reports new ads, price changes, edited, bumped and removed ads.
+
+Watches are loaded from YAML config file (--config) or a single watch is created from the query.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var watches []watch.Watch
+			if configFile != "" {
+				cfg, err := watch.LoadConfig(configFile)
+				if err != nil {
+					return err
+				}
+				watches = cfg.Watches
+			}
+			if len(args) > 0 {
+				query := strings.Join(args, " ")
+				watches = append(watches, watch.Watch{
+					Name: query,
+					Query: bazos.SearchQuery{
+						Query:    query,
+						Vicinity: bazos.DefaultVicinity,
+					},
+					Interval: interval,
+				})
+			}
+			if len(watches) == 0 {
+				return fmt.Errorf("no watches defined, use --config or pass query")
+			}
+
+			store, err := watch.NewStore(storeDir)
+			if err != nil {
+				return err
+			}
+			watcher := &watch.Watcher{
+				Client:         client,
+				Store:          store,
+				ListOptions:    bazos.ListOptions{MaxPages: maxPages, CollapseReposts: collapse},
+				NotifyExisting: notifyAll,
+			}
+			if historyDir != "" {
+				if watcher.History, err = history.NewStore(historyDir); err != nil {
+					return err
+				}
+			}
+
+			printEvent := func(e watch.Event) {
+				fmt.Printf("%s %s: %v\n", e.Time.Format(time.RFC3339), e.Watch, e)
+			}
+
+			if once {
+				for _, w := range watches {
+					events, err := watcher.Check(cmd.Context(), w)
+					if err != nil {
+						return err
+					}
+					for _, e := range events {
+						printEvent(e)
+					}
+				}
+				return nil
+			}
+			if err := watcher.Run(cmd.Context(), watches, printEvent); err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&configFile, "config", "", "YAML file with watches")
+	cmd.Flags().StringVar(&storeDir, "store", watch.DefaultStoreDir(), "Directory for storing seen ads")
+	cmd.Flags().DurationVar(&interval, "interval", watch.DefaultInterval, "Interval for checking the query")
+	cmd.Flags().BoolVar(&once, "once", false, "Check watches once and exit")
+	cmd.Flags().StringVar(&historyDir, "history", "", "Directory of the history store for recording observations (see stats command)")
+	cmd.Flags().BoolVar(&collapse, "collapse", false, "Collapse reposts of the same item found in a single check into the original ad (the oldest one)")
+	cmd.Flags().BoolVar(&notifyAll, "notify-existing", false, "Report ads found by the first check of a watch as new instead of only storing them")
+	cmd.Flags().IntVar(&maxPages, "max-pages", bazos.DefaultMaxPages, "Maximum number of result pages to fetch (-1 for no limit)")
+	return cmd
+}
@@ -0,0 +1,48 @@
+// Package fsutil provides file helpers shared by the bazos packages and commands.
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// WriteFileAtomic writes data to path with permissions perm. The data is written to
+// a temporary file in the same directory and renamed, so readers never see a partially
+// written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// CacheDir returns path of elem in the bazos directory of the user cache directory,
+// or of the temporary directory if the user has none.
+func CacheDir(elem ...string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(append([]string{dir, "bazos"}, elem...)...)
+}
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// SafeName replaces characters not safe in file names with underscores.
+func SafeName(s string) string {
+	return unsafeChars.ReplaceAllString(s, "_")
+}
@@ -0,0 +1,37 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Errorf("expected %q, got %q", data, got)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("expected mode 0644, got %v (%v)", info.Mode(), err)
+	}
+	// temporary files are removed
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the written file, got %d entries", len(entries))
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	if got := SafeName("pračky 2/elektro"); got != "pra_ky_2_elektro" {
+		t.Errorf("unexpected safe name %q", got)
+	}
+}